@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"order-service/internal/auth"
+	"order-service/internal/config"
+	"order-service/internal/database"
+	"time"
+)
+
+// orderservice apikey -name partner -role reader [-store]
+// печатает новый ключ и строку для AUTH_API_KEYS либо сохраняет хэш ключа в БД
+func runAPIKey(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	name := flags.String("name", "", "имя клиента, попадает в журналы")
+	roleName := flags.String("role", string(auth.RoleReader), "роль: reader, support или admin")
+	store := flags.Bool("store", false, "сохранить ключ в таблицу api_keys вместо вывода строки для AUTH_API_KEYS")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("не задано имя ключа (-name)")
+	}
+	role, err := auth.ParseRole(*roleName)
+	if err != nil {
+		return err
+	}
+
+	key, err := auth.GenerateKey()
+	if err != nil {
+		return err
+	}
+	hash := auth.HashKey(key)
+
+	if *store {
+		db, err := database.ConnectDB(cfg.DB)
+		if err != nil {
+			return fmt.Errorf("ошибка подключения к БД: %v", err)
+		}
+		defer db.CloseWithTimeout(10 * time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		stored, err := database.NewAPIKeyRepository(db.DB).CreateAPIKey(ctx, database.APIKey{
+			Name: *name, KeyHash: hash, Role: string(role), Active: true,
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Ключ %s (id %d, роль %s) сохранен в БД", stored.Name, stored.ID, stored.Role)
+	} else {
+		fmt.Printf("AUTH_API_KEYS: %s:%s:%s\n", *name, role, hash)
+	}
+
+	// ключ показывается один раз, в конфигурации и БД остается только хэш
+	fmt.Printf("API ключ: %s\n", key)
+	return nil
+}
+
+// аутентификатор HTTP API; db нужна, только если включены ключи в БД
+func newAuthenticator(cfg config.Config, db *sql.DB) (auth.Authenticator, error) {
+	keys, err := auth.ParseStaticKeys(cfg.Auth.APIKeys)
+	if err != nil {
+		return nil, fmt.Errorf("AUTH_API_KEYS: %w", err)
+	}
+	// прежний общий токен администратора продолжает работать как API ключ
+	if cfg.HTTP.AdminToken != "" {
+		keys = append(keys, auth.StaticKey{Name: "admin-token", Role: auth.RoleAdmin, Hash: auth.HashKey(cfg.HTTP.AdminToken)})
+	}
+
+	opts := auth.Options{
+		StaticKeys:    keys,
+		KeyCacheTTL:   cfg.Auth.KeyCacheTTL,
+		AnonymousRole: auth.Role(cfg.Auth.AnonymousRole),
+		JWT: auth.JWTOptions{
+			Issuer:    cfg.Auth.JWTIssuer,
+			Audience:  cfg.Auth.JWTAudience,
+			RoleClaim: cfg.Auth.JWTRoleClaim,
+			Leeway:    cfg.Auth.JWTLeeway,
+		},
+	}
+	if cfg.Auth.DBKeys {
+		opts.KeyStore = database.NewAPIKeyRepository(db)
+	}
+	if cfg.Auth.JWTSecret != "" {
+		opts.JWT.HMACSecret = []byte(cfg.Auth.JWTSecret)
+	}
+	if cfg.Auth.JWTPublicKeyFile != "" {
+		if opts.JWT.RSAPublicKey, err = auth.LoadRSAPublicKey(cfg.Auth.JWTPublicKeyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(keys) == 0 && !cfg.Auth.DBKeys && opts.JWT.HMACSecret == nil && opts.JWT.RSAPublicKey == nil {
+		log.Println("Не настроены API ключи и JWT: доступны только открытые маршруты")
+	}
+	if opts.AnonymousRole != "" {
+		log.Printf("Запросы без учетных данных получают роль %s", opts.AnonymousRole)
+	}
+	return auth.NewAuthenticator(opts)
+}
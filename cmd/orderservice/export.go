@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"order-service/internal/config"
+	"order-service/internal/database"
+	"order-service/internal/export"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// orderservice export -format csv -gzip -out orders.csv.gz -customer-id test -from 2024-01-01
+func runExport(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", export.FormatNDJSON, "формат выгрузки: ndjson или csv")
+	compress := flags.Bool("gzip", false, "сжимать вывод gzip")
+	out := flags.String("out", "-", "файл для выгрузки, - для stdout")
+	sort := flags.String("sort", "-"+database.SortByDateCreated, "сортировка: date_created или amount, - для убывания")
+	from := flags.String("from", "", "заказы начиная с даты YYYY-MM-DD или времени RFC3339")
+	to := flags.String("to", "", "заказы до даты YYYY-MM-DD включительно или времени RFC3339")
+
+	var filter database.OrderSearchFilter
+	flags.StringVar(&filter.CustomerID, "customer-id", "", "ID покупателя")
+	flags.StringVar(&filter.TrackNumber, "track-number", "", "трек-номер")
+	flags.StringVar(&filter.DeliveryService, "delivery-service", "", "служба доставки")
+	flags.StringVar(&filter.PaymentProvider, "provider", "", "платежный провайдер")
+	flags.StringVar(&filter.Currency, "currency", "", "валюта платежа")
+	flags.StringVar(&filter.Brand, "brand", "", "бренд товара")
+	flags.IntVar(&filter.NmID, "nm-id", 0, "nm_id товара")
+	flags.IntVar(&filter.MinAmount, "min-amount", 0, "минимальная сумма заказа")
+	flags.IntVar(&filter.MaxAmount, "max-amount", 0, "максимальная сумма заказа")
+	flags.IntVar(&filter.Limit, "limit", 0, "максимальное количество заказов, 0 - все")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filter.SortBy = strings.TrimPrefix(*sort, "-")
+	filter.Desc = strings.HasPrefix(*sort, "-")
+
+	var err error
+	if filter.CreatedFrom, err = database.ParseFilterTime(*from, false); err != nil {
+		return fmt.Errorf("from: %v", err)
+	}
+	if filter.CreatedTo, err = database.ParseFilterTime(*to, true); err != nil {
+		return fmt.Errorf("to: %v", err)
+	}
+
+	var output io.Writer = os.Stdout
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("ошибка создания файла: %v", err)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	writer, err := export.NewOrderWriter(output, *format, *compress)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.ConnectDB(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к БД: %v", err)
+	}
+	defer db.CloseWithTimeout(10 * time.Second)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	count := 0
+	err = database.NewOrderRepository(db.DB).ExportOrders(ctx, filter, func(order database.Order) error {
+		count++
+		return writer.WriteOrder(order)
+	})
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("выгрузка прервана после %d заказов: %w", count, err)
+	}
+
+	log.Printf("Выгружено заказов: %d", count)
+	return nil
+}
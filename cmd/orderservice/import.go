@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"order-service/internal/config"
+	"order-service/internal/database"
+	"order-service/internal/importer"
+	"order-service/internal/service"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// orderservice import -in orders.ndjson.gz -workers 4 -checkpoint import.checkpoint -reject rejected.ndjson
+func runImport(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	in := flags.String("in", "-", "файл NDJSON (можно сжатый gzip), - для stdin")
+	reportPath := flags.String("report", "", "файл для итогового отчета в JSON")
+	var opts importer.Options
+	flags.IntVar(&opts.BatchSize, "batch-size", 500, "заказов в одной транзакции")
+	flags.IntVar(&opts.Workers, "workers", 4, "параллельно сохраняемых пачек")
+	flags.StringVar(&opts.CheckpointPath, "checkpoint", "", "файл контрольной точки для возобновления импорта")
+	flags.StringVar(&opts.RejectPath, "reject", "", "файл для отклоненных записей")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if *in != "-" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return fmt.Errorf("ошибка открытия файла: %v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+	input, err := importer.OpenInput(input)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения входа: %v", err)
+	}
+
+	db, err := database.ConnectDB(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к БД: %v", err)
+	}
+	defer db.CloseWithTimeout(10 * time.Second)
+
+	// импорт не использует кэш, поэтому сервис создается без него
+	orderService := service.NewOrderService(database.NewOrderRepository(db.DB), nil, nil)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, importErr := importer.NewImporter(orderService, opts).Run(ctx, input)
+
+	data, _ := json.MarshalIndent(report, "", "  ")
+	log.Printf("Отчет импорта:\n%s", data)
+	if *reportPath != "" {
+		if err := os.WriteFile(*reportPath, append(data, '\n'), 0644); err != nil {
+			log.Printf("Ошибка сохранения отчета: %v", err)
+		}
+	}
+
+	if importErr != nil {
+		return fmt.Errorf("импорт остановлен: %w", importErr)
+	}
+	return nil
+}
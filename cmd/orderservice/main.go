@@ -3,12 +3,16 @@ package main
 import (
 	"context"
 	"log"
+	"order-service/internal/auth"
 	"order-service/internal/cache"
 	"order-service/internal/config"
 	"order-service/internal/database"
+	"order-service/internal/feed"
 	"order-service/internal/handler"
 	"order-service/internal/kafka"
+	"order-service/internal/pii"
 	"order-service/internal/service"
+	"order-service/internal/webhook"
 	"os"
 	"os/signal"
 	"sync"
@@ -25,6 +29,27 @@ func main() {
 
 	cfg := config.LoadConfig()
 
+	// подкоманды выполняются вместо запуска сервиса
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			if err := runExport(cfg, os.Args[2:]); err != nil {
+				log.Fatalf("Ошибка выгрузки: %v", err)
+			}
+			return
+		case "import":
+			if err := runImport(cfg, os.Args[2:]); err != nil {
+				log.Fatalf("Ошибка импорта: %v", err)
+			}
+			return
+		case "apikey":
+			if err := runAPIKey(cfg, os.Args[2:]); err != nil {
+				log.Fatalf("Ошибка создания API ключа: %v", err)
+			}
+			return
+		}
+	}
+
 	// Подключаемся к БД
 	db, err := database.ConnectDB(cfg.DB)
 	if err != nil {
@@ -46,31 +71,109 @@ func main() {
 	}
 	log.Println("Миграции успешно применены")
 
+	// проверка ключей и токенов HTTP API
+	authenticator, err := newAuthenticator(cfg, db.DB)
+	if err != nil {
+		log.Fatalf("Ошибка настройки аутентификации: %v", err)
+	}
+
 	// cоздаем репозиторий
 	orderRepo := database.NewOrderRepository(db.DB)
 
-	// cоздаем кэш
-	orderCache := cache.NewOrderCache(cfg.Cache.MaxSize, cfg.Cache.TTL)
+	// cоздаем кэш, при наличии Redis - двухуровневый
+	orderCache := cache.NewOrderCacheWithOptions(cache.OrderCacheOptions{
+		MaxSize:           cfg.Cache.MaxSize,
+		TTL:               cfg.Cache.TTL,
+		SlidingExpiration: cfg.Cache.Sliding,
+		TTLJitter:         cfg.Cache.TTLJitter,
+		RefreshAhead:      cfg.Cache.RefreshAhead,
+		Loader:            orderRepo.GetOrder,
+	})
+	if cfg.Redis.Addr != "" {
+		log.Printf("Подключаем L2 кэш Redis: %s", cfg.Redis.Addr)
+		orderCache = cache.NewTieredCache(orderCache, cache.NewRedisCache(cache.RedisOptions{
+			Addr:          cfg.Redis.Addr,
+			Password:      cfg.Redis.Password,
+			DB:            cfg.Redis.DB,
+			KeyPrefix:     cfg.Redis.KeyPrefix,
+			TTL:           cfg.Redis.TTL,
+			Timeout:       cfg.Redis.Timeout,
+			RetryInterval: cfg.Redis.RetryInterval,
+		}))
+	}
 	defer orderCache.Stop()
 
-	// восстанавливаем кэш из БД
-	if err := cache.RestoreCacheFromDB(db.DB, orderCache, cfg.Cache.RestoreLimit); err != nil {
-		log.Printf("Ошибка восстановления кэша: %v", err)
+	// кэш отсутствующих заказов
+	negativeCache := cache.NewNegativeCache(cfg.Cache.NegativeMaxSize, cfg.Cache.NegativeTTL)
+
+	// маскирование персональных данных покупателя
+	piiRules, err := pii.ParseRules(cfg.PII.MaskRules)
+	if err != nil {
+		log.Fatalf("Ошибка в PII_MASK_RULES: %v", err)
+	}
+	unmaskedRole, err := auth.ParseRole(cfg.PII.UnmaskedRole)
+	if err != nil {
+		log.Fatalf("Ошибка в PII_UNMASKED_ROLE: %v", err)
 	}
+	masker := pii.NewMasker(piiRules)
+	piiPolicy := handler.PIIPolicy{Masker: masker, UnmaskedRole: unmaskedRole}
 
 	// cоздаем сервис
-	orderService := service.NewOrderService(orderRepo, orderCache)
+	orderService := service.NewOrderService(orderRepo, orderCache, negativeCache)
+	orderService.SetMasker(masker)
+
+	// живая лента новых заказов для веб-интерфейса
+	orderFeed := feed.NewHub(cfg.HTTP.FeedBuffer)
+	orderService.AddOrderListener(orderFeed)
+
+	// уведомления партнеров о новых заказах
+	var webhooks webhook.Manager
+	if cfg.Webhook.Enabled {
+		webhooks = webhook.NewDispatcher(database.NewWebhookRepository(db.DB), webhook.Options{
+			Workers:      cfg.Webhook.Workers,
+			MaxAttempts:  cfg.Webhook.MaxAttempts,
+			Timeout:      cfg.Webhook.Timeout,
+			PollInterval: cfg.Webhook.PollInterval,
+			RetryBase:    cfg.Webhook.RetryBase,
+			RetryMax:     cfg.Webhook.RetryMax,
+		})
+		orderService.AddOrderOutbox(webhooks)
+		orderService.AddOrderListener(webhooks)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	var wg sync.WaitGroup
 
+	if webhooks != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			webhooks.Run(ctx)
+		}()
+	}
+
+	// прогреваем кэш в фоне: из снимка, если его нет или он устарел - из БД
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := orderService.WarmCache(ctx, cache.WarmupOptions{
+			Limit:          cfg.Cache.RestoreLimit,
+			BatchSize:      cfg.Cache.RestoreBatchSize,
+			SnapshotPath:   cfg.Cache.SnapshotPath,
+			SnapshotMaxAge: cfg.Cache.SnapshotMaxAge,
+		})
+		if err != nil {
+			log.Printf("Ошибка прогрева кэша: %v", err)
+		}
+	}()
+
 	// запускаем HTTP сервер
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		handler.StartHTTPServer(ctx, orderService, cfg.HTTP.Port)
+		handler.StartHTTPServer(ctx, orderService, webhooks, orderFeed, authenticator, piiPolicy, cfg.HTTP)
 	}()
 
 	// запускаем Kafka
@@ -80,6 +183,15 @@ func main() {
 		kafka.StartKafkaConsumer(ctx, cfg.Kafka, orderService)
 	}()
 
+	// сбрасываем кэш при изменении заказов другими репликами или напрямую в БД
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := database.ListenOrderChanges(ctx, cfg.DB, orderService.HandleOrderChange); err != nil {
+			log.Printf("Ошибка подписки на изменения заказов: %v", err)
+		}
+	}()
+
 	log.Println("Для остановки нажмите Ctrl+C")
 
 	// ожидание сигналов завершения
@@ -92,7 +204,14 @@ func main() {
 	cancel()
 
 	wg.Wait()
-	log.Println("HTTP сервер и Kafka consumer остановлены")
+	log.Println("HTTP сервер, Kafka consumer и прогрев кэша остановлены")
+
+	// сохраняем снимок кэша для быстрого перезапуска
+	if cfg.Cache.SnapshotPath != "" {
+		if err := cache.SaveCacheSnapshot(orderCache, cfg.Cache.SnapshotPath); err != nil {
+			log.Printf("Ошибка сохранения снимка кэша: %v", err)
+		}
+	}
 
 	log.Println("Все компоненты остановлены")
 	time.Sleep(100 * time.Millisecond)
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"order-service/internal/database"
+	"order-service/internal/ttlcache"
+	"strings"
+	"time"
+)
+
+// префикс выдаваемых ключей, по нему ключ легко найти в логах и репозиториях
+const keyPrefix = "osk_"
+
+// SHA-256 ключа в hex; ключи случайные и длинные, медленный хэш не нужен
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// новый случайный ключ
+func GenerateKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("ошибка генерации ключа: %w", err)
+	}
+	return keyPrefix + hex.EncodeToString(buf), nil
+}
+
+// ключ из конфигурации
+type StaticKey struct {
+	Name string
+	Role Role
+	Hash string
+}
+
+// разбирает список "имя:роль:sha256" через запятую
+func ParseStaticKeys(spec string) ([]StaticKey, error) {
+	var keys []StaticKey
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("ключ %q: ожидается формат имя:роль:sha256", entry)
+		}
+		role, err := ParseRole(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("ключ %s: %w", parts[0], err)
+		}
+		hash := strings.ToLower(parts[2])
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("ключ %s: ожидается SHA-256 в hex", parts[0])
+		}
+		keys = append(keys, StaticKey{Name: parts[0], Role: role, Hash: hash})
+	}
+	return keys, nil
+}
+
+// проверяет ключи из конфигурации и, если задано хранилище, из таблицы api_keys
+type keyAuthenticator struct {
+	static []StaticKey
+	store  database.APIKeyRepository
+	// результаты поиска в БД по хэшу, включая отсутствующие ключи
+	cache *ttlcache.Cache[string, *Principal]
+}
+
+func newKeyAuthenticator(static []StaticKey, store database.APIKeyRepository, cacheTTL time.Duration) *keyAuthenticator {
+	a := &keyAuthenticator{static: static, store: store}
+	if store != nil && cacheTTL > 0 {
+		// истекшие записи вытесняются по размеру, фоновая очистка не нужна
+		a.cache = ttlcache.New(ttlcache.Options[string, *Principal]{MaxSize: 10000, TTL: cacheTTL})
+	}
+	return a
+}
+
+func (a *keyAuthenticator) authenticate(ctx context.Context, key string) (Principal, error) {
+	hash := HashKey(key)
+
+	// сравниваем все ключи за постоянное время, чтобы не выдавать совпадение префикса
+	var found *StaticKey
+	for i := range a.static {
+		if subtle.ConstantTimeCompare([]byte(a.static[i].Hash), []byte(hash)) == 1 {
+			found = &a.static[i]
+		}
+	}
+	if found != nil {
+		return Principal{Subject: found.Name, Role: found.Role, Method: MethodAPIKey}, nil
+	}
+
+	if a.store == nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	if a.cache != nil {
+		if cached, ok := a.cache.Get(hash); ok {
+			if cached == nil {
+				return Principal{}, ErrInvalidCredentials
+			}
+			return *cached, nil
+		}
+	}
+
+	stored, err := a.store.GetAPIKeyByHash(ctx, hash)
+	if errors.Is(err, database.ErrAPIKeyNotFound) {
+		if a.cache != nil {
+			a.cache.Set(hash, nil)
+		}
+		return Principal{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+
+	role, err := ParseRole(stored.Role)
+	if err != nil {
+		log.Printf("API ключ %s отклонен: %v", stored.Name, err)
+		return Principal{}, ErrInvalidCredentials
+	}
+	principal := Principal{Subject: stored.Name, Role: role, Method: MethodAPIKey}
+	if a.cache != nil {
+		a.cache.Set(hash, &principal)
+	}
+	if err := a.store.TouchAPIKey(ctx, stored.ID); err != nil {
+		log.Printf("Не удалось отметить использование API ключа %s: %v", stored.Name, err)
+	}
+	return principal, nil
+}
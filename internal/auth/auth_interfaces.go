@@ -0,0 +1,9 @@
+package auth
+
+import "context"
+
+// проверяет учетные данные запроса и определяет роль вызывающего
+type Authenticator interface {
+	// ErrNoCredentials, если учетных данных нет, ErrInvalidCredentials, если они не подходят
+	Authenticate(ctx context.Context, creds Credentials) (Principal, error)
+}
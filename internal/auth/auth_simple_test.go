@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"order-service/internal/database"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// хранилище ключей в памяти
+type mockKeyStore struct {
+	keys    map[string]database.APIKey
+	lookups int
+	touched []int64
+}
+
+func (m *mockKeyStore) CreateAPIKey(ctx context.Context, key database.APIKey) (database.APIKey, error) {
+	key.ID = int64(len(m.keys) + 1)
+	m.keys[key.KeyHash] = key
+	return key, nil
+}
+
+func (m *mockKeyStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (database.APIKey, error) {
+	m.lookups++
+	key, ok := m.keys[keyHash]
+	if !ok || !key.Active {
+		return database.APIKey{}, database.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (m *mockKeyStore) TouchAPIKey(ctx context.Context, id int64) error {
+	m.touched = append(m.touched, id)
+	return nil
+}
+
+func TestRoleAllows(t *testing.T) {
+	if !RoleAdmin.Allows(RoleReader) || !RoleSupport.Allows(RoleSupport) {
+		t.Error("Старшая роль должна включать права младших")
+	}
+	if RoleReader.Allows(RoleSupport) || Role("guest").Allows(RoleReader) {
+		t.Error("Младшая или неизвестная роль не должна проходить проверку")
+	}
+	if _, err := ParseRole("root"); err == nil {
+		t.Error("Ожидалась ошибка для неизвестной роли")
+	}
+}
+
+func TestParseStaticKeys(t *testing.T) {
+	hash := HashKey("secret")
+	keys, err := ParseStaticKeys(" ci:reader:" + hash + ", ops:admin:" + strings.ToUpper(hash) + ",")
+	if err != nil {
+		t.Fatalf("Ошибка разбора ключей: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Name != "ci" || keys[1].Role != RoleAdmin || keys[1].Hash != hash {
+		t.Errorf("Неверные ключи: %+v", keys)
+	}
+
+	for _, spec := range []string{"ci:reader", "ci:root:" + hash, "ci:reader:secret"} {
+		if _, err := ParseStaticKeys(spec); err == nil {
+			t.Errorf("%q: ожидалась ошибка", spec)
+		}
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	store := &mockKeyStore{keys: map[string]database.APIKey{
+		HashKey("db-key"):      {ID: 7, Name: "partner", KeyHash: HashKey("db-key"), Role: "support", Active: true},
+		HashKey("revoked-key"): {ID: 8, Name: "old", KeyHash: HashKey("revoked-key"), Role: "admin"},
+	}}
+	authn, err := NewAuthenticator(Options{
+		StaticKeys:  []StaticKey{{Name: "ci", Role: RoleReader, Hash: HashKey("static-key")}},
+		KeyStore:    store,
+		KeyCacheTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания аутентификатора: %v", err)
+	}
+	ctx := context.Background()
+
+	principal, err := authn.Authenticate(ctx, Credentials{APIKey: "static-key"})
+	if err != nil || principal.Subject != "ci" || principal.Role != RoleReader || principal.Method != MethodAPIKey {
+		t.Errorf("Ключ из конфигурации не принят: %+v, %v", principal, err)
+	}
+	if store.lookups != 0 {
+		t.Error("Ключ из конфигурации не должен искаться в БД")
+	}
+
+	// ключ из БД принимается и как Bearer, повторная проверка берется из кэша
+	for range 3 {
+		principal, err = authn.Authenticate(ctx, Credentials{BearerToken: "db-key"})
+		if err != nil || principal.Subject != "partner" || principal.Role != RoleSupport {
+			t.Fatalf("Ключ из БД не принят: %+v, %v", principal, err)
+		}
+	}
+	if store.lookups != 1 || len(store.touched) != 1 || store.touched[0] != 7 {
+		t.Errorf("Ожидался 1 поиск и 1 отметка использования, получено %d и %v", store.lookups, store.touched)
+	}
+
+	for _, key := range []string{"unknown", "unknown", "revoked-key"} {
+		if _, err := authn.Authenticate(ctx, Credentials{APIKey: key}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: ожидалась ErrInvalidCredentials, получено %v", key, err)
+		}
+	}
+	// отсутствующий ключ тоже кэшируется
+	if store.lookups != 3 {
+		t.Errorf("Ожидалось 3 поиска в БД, получено %d", store.lookups)
+	}
+
+	if _, err := authn.Authenticate(ctx, Credentials{}); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Ожидалась ErrNoCredentials, получено %v", err)
+	}
+}
+
+func TestAnonymousRole(t *testing.T) {
+	if _, err := NewAuthenticator(Options{AnonymousRole: "guest"}); err == nil {
+		t.Error("Ожидалась ошибка для неизвестной анонимной роли")
+	}
+
+	authn, _ := NewAuthenticator(Options{AnonymousRole: RoleReader})
+	principal, err := authn.Authenticate(context.Background(), Credentials{})
+	if err != nil || principal.Role != RoleReader || principal.Method != MethodAnonymous {
+		t.Errorf("Ожидался анонимный reader: %+v, %v", principal, err)
+	}
+	// неверный ключ не понижается до анонимного доступа
+	if _, err := authn.Authenticate(context.Background(), Credentials{APIKey: "wrong"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Ожидалась ErrInvalidCredentials, получено %v", err)
+	}
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	secret := []byte("jwt-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Ошибка генерации RSA ключа: %v", err)
+	}
+	authn, err := NewAuthenticator(Options{JWT: JWTOptions{
+		HMACSecret:   secret,
+		RSAPublicKey: &rsaKey.PublicKey,
+		Issuer:       "https://id.example",
+		Audience:     "order-service",
+	}})
+	if err != nil {
+		t.Fatalf("Ошибка создания аутентификатора: %v", err)
+	}
+
+	claims := func(extra jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":  "alice",
+			"role": "support",
+			"iss":  "https://id.example",
+			"aud":  "order-service",
+			"exp":  time.Now().Add(time.Hour).Unix(),
+		}
+		for key, value := range extra {
+			if value == nil {
+				delete(c, key)
+			} else {
+				c[key] = value
+			}
+		}
+		return c
+	}
+	sign := func(method jwt.SigningMethod, key interface{}, c jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(method, c).SignedString(key)
+		if err != nil {
+			t.Fatalf("Ошибка подписи токена: %v", err)
+		}
+		return token
+	}
+	authenticate := func(token string) (Principal, error) {
+		return authn.Authenticate(context.Background(), Credentials{BearerToken: token})
+	}
+
+	principal, err := authenticate(sign(jwt.SigningMethodHS256, secret, claims(nil)))
+	if err != nil || principal.Subject != "alice" || principal.Role != RoleSupport || principal.Method != MethodJWT {
+		t.Errorf("HS256 токен не принят: %+v, %v", principal, err)
+	}
+
+	// из массива ролей берется старшая известная
+	rolesAuthn, _ := NewAuthenticator(Options{JWT: JWTOptions{RSAPublicKey: &rsaKey.PublicKey, RoleClaim: "roles"}})
+	principal, err = rolesAuthn.Authenticate(context.Background(), Credentials{
+		BearerToken: sign(jwt.SigningMethodRS256, rsaKey, claims(jwt.MapClaims{"roles": []string{"reader", "auditor", "admin"}})),
+	})
+	if err != nil || principal.Role != RoleAdmin {
+		t.Errorf("RS256 токен с массивом ролей не принят: %+v, %v", principal, err)
+	}
+
+	rejected := map[string]string{
+		"истек":            sign(jwt.SigningMethodHS256, secret, claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"без exp":          sign(jwt.SigningMethodHS256, secret, claims(jwt.MapClaims{"exp": nil})),
+		"чужой издатель":   sign(jwt.SigningMethodHS256, secret, claims(jwt.MapClaims{"iss": "https://other.example"})),
+		"чужая аудитория":  sign(jwt.SigningMethodHS256, secret, claims(jwt.MapClaims{"aud": "billing"})),
+		"неизвестная роль": sign(jwt.SigningMethodHS256, secret, claims(jwt.MapClaims{"role": "root"})),
+		"без sub":          sign(jwt.SigningMethodHS256, secret, claims(jwt.MapClaims{"sub": nil})),
+		"другой секрет":    sign(jwt.SigningMethodHS256, []byte("other"), claims(nil)),
+		"alg none":         sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims(nil)),
+		"не JWT":           "a.b.c",
+		"ES256 без ключа":  "eyJhbGciOiJFUzI1NiJ9.e30.sig",
+	}
+	for name, token := range rejected {
+		if _, err := authenticate(token); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: ожидалась ErrInvalidCredentials, получено %v", name, err)
+		}
+	}
+
+	// без настроенного JWT токен отклоняется, а не ищется как API ключ
+	keysOnly, _ := NewAuthenticator(Options{})
+	if _, err := keysOnly.Authenticate(context.Background(), Credentials{BearerToken: sign(jwt.SigningMethodHS256, secret, claims(nil))}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Ожидалась ErrInvalidCredentials без настроенного JWT, получено %v", err)
+	}
+}
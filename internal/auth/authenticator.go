@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"order-service/internal/database"
+	"strings"
+	"time"
+)
+
+// источники учетных данных; все поля необязательны
+type Options struct {
+	StaticKeys []StaticKey
+	// ключи из таблицы api_keys
+	KeyStore database.APIKeyRepository
+	// сколько помнить результат поиска ключа в БД, 0 - искать каждый раз
+	KeyCacheTTL time.Duration
+	JWT         JWTOptions
+	// роль запросов без учетных данных, пусто - такие запросы отклоняются
+	AnonymousRole Role
+}
+
+type authenticator struct {
+	keys      *keyAuthenticator
+	jwt       *jwtAuthenticator
+	anonymous Role
+}
+
+// создает аутентификатор по API ключам и JWT
+func NewAuthenticator(opts Options) (Authenticator, error) {
+	if opts.AnonymousRole != "" {
+		if _, err := ParseRole(string(opts.AnonymousRole)); err != nil {
+			return nil, err
+		}
+	}
+
+	a := &authenticator{
+		keys:      newKeyAuthenticator(opts.StaticKeys, opts.KeyStore, opts.KeyCacheTTL),
+		anonymous: opts.AnonymousRole,
+	}
+	if opts.JWT.enabled() {
+		a.jwt = newJWTAuthenticator(opts.JWT)
+	}
+	return a, nil
+}
+
+func (a *authenticator) Authenticate(ctx context.Context, creds Credentials) (Principal, error) {
+	switch {
+	case creds.APIKey != "":
+		return a.keys.authenticate(ctx, creds.APIKey)
+	case creds.BearerToken != "":
+		// JWT состоит из трех частей через точку, в API ключах точек нет
+		if strings.Count(creds.BearerToken, ".") == 2 {
+			if a.jwt == nil {
+				return Principal{}, fmt.Errorf("%w: проверка JWT не настроена", ErrInvalidCredentials)
+			}
+			return a.jwt.authenticate(creds.BearerToken)
+		}
+		return a.keys.authenticate(ctx, creds.BearerToken)
+	case a.anonymous != "":
+		return Principal{Subject: MethodAnonymous, Role: a.anonymous, Method: MethodAnonymous}, nil
+	default:
+		return Principal{}, ErrNoCredentials
+	}
+}
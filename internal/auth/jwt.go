@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// параметры проверки JWT; нужен хотя бы один из ключей
+type JWTOptions struct {
+	// общий секрет для HS256/HS384/HS512
+	HMACSecret []byte
+	// публичный ключ для RS256/RS384/RS512
+	RSAPublicKey *rsa.PublicKey
+	// если заданы, claim iss/aud должен совпадать
+	Issuer   string
+	Audience string
+	// claim с ролью: строка или массив строк, по умолчанию "role"
+	RoleClaim string
+	// допустимое расхождение часов при проверке exp/nbf/iat
+	Leeway time.Duration
+}
+
+func (o JWTOptions) enabled() bool {
+	return len(o.HMACSecret) > 0 || o.RSAPublicKey != nil
+}
+
+// читает публичный RSA ключ в PEM
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения публичного ключа: %w", err)
+	}
+	key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора публичного ключа: %w", err)
+	}
+	return key, nil
+}
+
+type jwtAuthenticator struct {
+	opts   JWTOptions
+	parser *jwt.Parser
+}
+
+func newJWTAuthenticator(opts JWTOptions) *jwtAuthenticator {
+	if opts.RoleClaim == "" {
+		opts.RoleClaim = "role"
+	}
+
+	// алгоритм фиксируется типом ключа, иначе токен с alg=HS256 можно подписать публичным RSA ключом
+	var methods []string
+	if len(opts.HMACSecret) > 0 {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if opts.RSAPublicKey != nil {
+		methods = append(methods, "RS256", "RS384", "RS512")
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	return &jwtAuthenticator{opts: opts, parser: jwt.NewParser(parserOpts...)}
+}
+
+func (a *jwtAuthenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return a.opts.HMACSecret, nil
+	case *jwt.SigningMethodRSA:
+		return a.opts.RSAPublicKey, nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый алгоритм %s", token.Method.Alg())
+	}
+}
+
+func (a *jwtAuthenticator) authenticate(tokenString string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(tokenString, claims, a.keyFunc); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return Principal{}, fmt.Errorf("%w: в токене нет sub", ErrInvalidCredentials)
+	}
+	role, err := roleFromClaim(claims[a.opts.RoleClaim])
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return Principal{Subject: subject, Role: role, Method: MethodJWT}, nil
+}
+
+// из массива ролей выбирается старшая; неизвестные роли пропускаются
+func roleFromClaim(value interface{}) (Role, error) {
+	var values []interface{}
+	switch v := value.(type) {
+	case string:
+		values = []interface{}{v}
+	case []interface{}:
+		values = v
+	case nil:
+		return "", errors.New("в токене нет роли")
+	default:
+		return "", fmt.Errorf("роль в токене имеет тип %T", value)
+	}
+
+	var best Role
+	for _, item := range values {
+		name, ok := item.(string)
+		if !ok {
+			continue
+		}
+		role, err := ParseRole(name)
+		if err == nil && roleRanks[role] > roleRanks[best] {
+			best = role
+		}
+	}
+	if best == "" {
+		return "", errors.New("в токене нет известной роли")
+	}
+	return best, nil
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// запрос без ключа и токена
+	ErrNoCredentials = errors.New("учетные данные не переданы")
+	// ключ неизвестен или токен не прошел проверку
+	ErrInvalidCredentials = errors.New("неверные учетные данные")
+	// роль вызывающего ниже требуемой
+	ErrForbidden = errors.New("недостаточно прав")
+)
+
+// роль клиента; каждая следующая включает права предыдущих
+type Role string
+
+const (
+	// чтение заказов, поиск, выгрузка, аналитика
+	RoleReader Role = "reader"
+	// прием заказов и доступ к журналу вебхуков
+	RoleSupport Role = "support"
+	// управление кэшем, бенчмарк, вебхуки и их повторная отправка
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{RoleReader: 1, RoleSupport: 2, RoleAdmin: 3}
+
+func ParseRole(value string) (Role, error) {
+	role := Role(value)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("неизвестная роль %q: допустимы reader, support, admin", value)
+	}
+	return role, nil
+}
+
+// true, если роль включает права required
+func (r Role) Allows(required Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[required]
+}
+
+// способы аутентификации
+const (
+	MethodAPIKey    = "api_key"
+	MethodJWT       = "jwt"
+	MethodAnonymous = "anonymous"
+)
+
+// аутентифицированный клиент
+type Principal struct {
+	// имя ключа или subject токена
+	Subject string `json:"subject"`
+	Role    Role   `json:"role"`
+	Method  string `json:"method"`
+}
+
+// учетные данные запроса: API ключ или bearer токен (JWT или API ключ)
+type Credentials struct {
+	APIKey      string
+	BearerToken string
+}
+
+func (c Credentials) Empty() bool {
+	return c.APIKey == "" && c.BearerToken == ""
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// клиент запроса; ok=false, если запрос не проходил аутентификацию
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
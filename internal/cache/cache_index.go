@@ -0,0 +1,83 @@
+package cache
+
+import "order-service/internal/database"
+
+// вторичный индекс кэша: значение поля -> множество order_uid
+type orderIndex map[string]map[string]struct{}
+
+func (idx orderIndex) add(value, orderUID string) {
+	if value == "" {
+		return
+	}
+	uids, ok := idx[value]
+	if !ok {
+		uids = make(map[string]struct{})
+		idx[value] = uids
+	}
+	uids[orderUID] = struct{}{}
+}
+
+func (idx orderIndex) remove(value, orderUID string) {
+	uids, ok := idx[value]
+	if !ok {
+		return
+	}
+	delete(uids, orderUID)
+	if len(uids) == 0 {
+		delete(idx, value)
+	}
+}
+
+func (idx orderIndex) lookup(value string) []string {
+	uids := make([]string, 0, len(idx[value]))
+	for orderUID := range idx[value] {
+		uids = append(uids, orderUID)
+	}
+	return uids
+}
+
+// возвращает закэшированные заказы с указанным трек-номером
+func (oc *OrderCache) GetByTrackNumber(trackNumber string) []database.Order {
+	oc.indexMutex.RLock()
+	uids := oc.trackIndex.lookup(trackNumber)
+	oc.indexMutex.RUnlock()
+	return oc.getMany(uids, func(order database.Order) bool {
+		return order.TrackNumber == trackNumber
+	})
+}
+
+// возвращает закэшированные заказы покупателя
+func (oc *OrderCache) GetByCustomerID(customerID string) []database.Order {
+	oc.indexMutex.RLock()
+	uids := oc.customerIndex.lookup(customerID)
+	oc.indexMutex.RUnlock()
+	return oc.getMany(uids, func(order database.Order) bool {
+		return order.CustomerID == customerID
+	})
+}
+
+// индекс читается отдельно от записей, поэтому заказ мог смениться между lookup и Get
+func (oc *OrderCache) getMany(uids []string, matches func(order database.Order) bool) []database.Order {
+	orders := make([]database.Order, 0, len(uids))
+	for _, orderUID := range uids {
+		if order, found := oc.Get(orderUID); found && matches(order) {
+			orders = append(orders, order)
+		}
+	}
+	return orders
+}
+
+// вызываются из колбэков ttlcache под блокировкой записей
+func (oc *OrderCache) indexOrder(order database.Order) {
+	oc.indexMutex.Lock()
+	defer oc.indexMutex.Unlock()
+	oc.trackIndex.add(order.TrackNumber, order.OrderUID)
+	oc.customerIndex.add(order.CustomerID, order.OrderUID)
+}
+
+func (oc *OrderCache) unindexOrder(order database.Order) {
+	oc.indexMutex.Lock()
+	defer oc.indexMutex.Unlock()
+	oc.trackIndex.remove(order.TrackNumber, order.OrderUID)
+	oc.customerIndex.remove(order.CustomerID, order.OrderUID)
+}
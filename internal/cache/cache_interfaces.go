@@ -1,7 +1,7 @@
 package cache
 
 import (
-	"database/sql"
+	"context"
 	"order-service/internal/database"
 	"time"
 )
@@ -9,15 +9,36 @@ import (
 // интерфейс для кэша
 type Cache interface {
 	Get(orderUID string) (database.Order, bool)
+	// как Get, но без учета в статистике и без продления срока жизни записи
+	Peek(orderUID string) (database.Order, bool)
 	Set(order database.Order)
-	Delete(orderUID string)
+	// возвращает false, если заказа в кэше не было
+	Delete(orderUID string) bool
+	// удаляет все записи, возвращает количество удаленных
+	Flush() int
 	Size() int
 	Cleanup(ttl time.Duration)
 	Stop()
-	Range(f func(key, value interface{}) bool)
+	Range(f func(orderUID string, cached CachedOrder) bool)
+	// страница записей, сначала самые новые, и общее количество записей; limit <= 0 - до конца
+	Entries(offset, limit int) ([]CachedOrder, int)
+	GetByTrackNumber(trackNumber string) []database.Order
+	GetByCustomerID(customerID string) []database.Order
+	Stats() CacheStats
+	RecordLoad(duration time.Duration, err error)
 }
 
-// интерфейс для восстановления кэша из БД
+// интерфейс для кэша отсутствующих заказов
+type NegativeCache interface {
+	Add(orderUID string)
+	Contains(orderUID string) bool
+	Remove(orderUID string)
+	Clear()
+	Size() int
+}
+
+// интерфейс для прогрева кэша
 type CacheRestorer interface {
-	RestoreCacheFromDB(db *sql.DB, cache Cache, limit int) error
+	Run(ctx context.Context) error
+	Progress() WarmupProgress
 }
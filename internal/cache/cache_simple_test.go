@@ -1,7 +1,14 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"order-service/internal/database"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -77,3 +84,327 @@ func TestCacheExpiration(t *testing.T) {
 		t.Error("Заказ все еще в кэше после истечения TTL")
 	}
 }
+
+func TestNegativeCache(t *testing.T) {
+	negativeCache := NewNegativeCache(2, 100*time.Millisecond)
+
+	negativeCache.Add("missing1")
+	if !negativeCache.Contains("missing1") {
+		t.Error("Отсутствующий заказ не найден в негативном кэше")
+	}
+
+	// при переполнении вытесняется самая старая запись
+	negativeCache.Add("missing2")
+	negativeCache.Add("missing3")
+	if negativeCache.Size() != 2 {
+		t.Errorf("Ожидался размер негативного кэша 2, получен %d", negativeCache.Size())
+	}
+	if negativeCache.Contains("missing1") {
+		t.Error("Самая старая запись не была вытеснена")
+	}
+
+	// заказ появился в БД
+	negativeCache.Remove("missing2")
+	if negativeCache.Contains("missing2") {
+		t.Error("Запись все еще в негативном кэше после удаления")
+	}
+
+	// после истечения TTL запись пропадает
+	time.Sleep(150 * time.Millisecond)
+	if negativeCache.Contains("missing3") {
+		t.Error("Запись все еще в негативном кэше после истечения TTL")
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	cache := NewOrderCache(2, 100*time.Millisecond)
+	defer cache.Stop()
+
+	cache.Set(database.Order{OrderUID: "a"})
+	cache.Set(database.Order{OrderUID: "b"})
+
+	cache.Get("a")
+	cache.Get("missing")
+
+	// третий заказ вытесняет самый старый
+	cache.Set(database.Order{OrderUID: "c"})
+	cache.Delete("c")
+	cache.RecordLoad(20*time.Millisecond, nil)
+
+	// ждем истечения TTL оставшейся записи
+	time.Sleep(150 * time.Millisecond)
+	cache.Get("b")
+
+	stats := cache.Stats()
+	if stats.Hits != 1 {
+		t.Errorf("Ожидалось 1 попадание, получено %d", stats.Hits)
+	}
+	if stats.Misses != 2 {
+		t.Errorf("Ожидалось 2 промаха, получено %d", stats.Misses)
+	}
+	if stats.Expirations != 1 {
+		t.Errorf("Ожидалось 1 истечение, получено %d", stats.Expirations)
+	}
+	if stats.Evictions.Capacity != 1 || stats.Evictions.Manual != 1 || stats.Evictions.TTL != 1 {
+		t.Errorf("Неверная статистика вытеснений: %+v", stats.Evictions)
+	}
+	if stats.Loads != 1 || stats.LoadTimeAvg != 20*time.Millisecond {
+		t.Errorf("Неверная статистика загрузок: %d, %v", stats.Loads, stats.LoadTimeAvg)
+	}
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	source := NewOrderCache(10, time.Minute)
+	defer source.Stop()
+	source.Set(database.Order{OrderUID: "snap1", TrackNumber: "TRACK1"})
+	source.Set(database.Order{OrderUID: "snap2", TrackNumber: "TRACK2"})
+
+	if err := SaveCacheSnapshot(source, path); err != nil {
+		t.Fatalf("Ошибка сохранения снимка: %v", err)
+	}
+
+	// свежий снимок загружается полностью
+	restored := NewOrderCache(10, time.Minute)
+	defer restored.Stop()
+	count, err := RestoreCacheFromSnapshot(restored, path, time.Minute)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки снимка: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Ожидалось 2 заказа из снимка, получено %d", count)
+	}
+	if order, found := restored.Get("snap2"); !found || order.TrackNumber != "TRACK2" {
+		t.Errorf("Заказ из снимка восстановлен неверно: %+v", order)
+	}
+
+	// устаревший снимок отклоняется
+	if _, err := RestoreCacheFromSnapshot(restored, path, 0); !errors.Is(err, ErrSnapshotStale) {
+		t.Errorf("Ожидалась ошибка ErrSnapshotStale, получено: %v", err)
+	}
+
+	// поврежденный снимок отклоняется
+	data, _ := os.ReadFile(path)
+	data[len(data)-10] ^= 0xFF
+	os.WriteFile(path, data, 0o644)
+	if _, err := RestoreCacheFromSnapshot(restored, path, time.Minute); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Errorf("Ожидалась ошибка ErrSnapshotCorrupt, получено: %v", err)
+	}
+}
+
+// mock репозитория, отдающий заказы страницами
+type streamRepoMock struct {
+	database.OrderRepository
+	orders []database.Order
+}
+
+func (m *streamRepoMock) StreamOrders(ctx context.Context, limit, batchSize int, fn func(orders []database.Order) error) error {
+	orders := m.orders[:min(limit, len(m.orders))]
+	for start := 0; start < len(orders); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(orders[start:min(start+batchSize, len(orders))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCacheWarmer(t *testing.T) {
+	repo := &streamRepoMock{}
+	for i := 0; i < 5; i++ {
+		repo.orders = append(repo.orders, database.Order{OrderUID: fmt.Sprintf("warm%d", i)})
+	}
+
+	cache := NewOrderCache(10, time.Minute)
+	defer cache.Stop()
+
+	warmer := NewCacheWarmer(repo, cache, WarmupOptions{Limit: 4, BatchSize: 3})
+	if warmer.Progress().Done() {
+		t.Error("Прогрев завершен до запуска")
+	}
+
+	if err := warmer.Run(context.Background()); err != nil {
+		t.Fatalf("Ошибка прогрева: %v", err)
+	}
+
+	progress := warmer.Progress()
+	if progress.State != WarmupCompleted || progress.Source != "database" || progress.Loaded != 4 {
+		t.Errorf("Неверное состояние прогрева: %+v", progress)
+	}
+	if cache.Size() != 4 {
+		t.Errorf("Ожидался размер кэша 4, получен %d", cache.Size())
+	}
+
+	// отмена контекста прерывает прогрев
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelledCache := NewOrderCache(10, time.Minute)
+	defer cancelledCache.Stop()
+	cancelled := NewCacheWarmer(repo, cancelledCache, WarmupOptions{Limit: 5, BatchSize: 2})
+	if err := cancelled.Run(ctx); err == nil {
+		t.Error("Ожидалась ошибка отмены прогрева")
+	}
+	if cancelled.Progress().State != WarmupCancelled {
+		t.Errorf("Ожидалось состояние cancelled, получено %s", cancelled.Progress().State)
+	}
+}
+
+func TestCacheSecondaryIndexes(t *testing.T) {
+	cache := NewOrderCache(2, time.Minute)
+	defer cache.Stop()
+
+	cache.Set(database.Order{OrderUID: "idx1", TrackNumber: "TRACK1", CustomerID: "customer1"})
+	cache.Set(database.Order{OrderUID: "idx2", TrackNumber: "TRACK2", CustomerID: "customer1"})
+
+	if orders := cache.GetByCustomerID("customer1"); len(orders) != 2 {
+		t.Errorf("Ожидалось 2 заказа покупателя, получено %d", len(orders))
+	}
+
+	// при обновлении заказа индекс перестраивается
+	cache.Set(database.Order{OrderUID: "idx2", TrackNumber: "TRACK2B", CustomerID: "customer1"})
+	if orders := cache.GetByTrackNumber("TRACK2"); len(orders) != 0 {
+		t.Errorf("Старый трек-номер остался в индексе: %v", orders)
+	}
+	if orders := cache.GetByTrackNumber("TRACK2B"); len(orders) != 1 {
+		t.Errorf("Новый трек-номер не найден в индексе")
+	}
+
+	// вытесненный и удаленный заказы пропадают из индексов
+	cache.Set(database.Order{OrderUID: "idx3", TrackNumber: "TRACK3", CustomerID: "customer2"})
+	cache.Delete("idx2")
+	if orders := cache.GetByCustomerID("customer1"); len(orders) != 0 {
+		t.Errorf("Удаленные заказы остались в индексе покупателя: %v", orders)
+	}
+	if orders := cache.GetByTrackNumber("TRACK3"); len(orders) != 1 || orders[0].OrderUID != "idx3" {
+		t.Errorf("Заказ не найден по трек-номеру: %v", orders)
+	}
+}
+
+// управляемые часы для тестов истечения записей
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	c.now = c.now.Add(d)
+	c.mutex.Unlock()
+}
+
+func TestCacheSlidingExpiration(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	cache := NewOrderCacheWithOptions(OrderCacheOptions{
+		MaxSize:           10,
+		TTL:               time.Minute,
+		SlidingExpiration: true,
+		Clock:             clock,
+	})
+	defer cache.Stop()
+
+	cache.Set(database.Order{OrderUID: "sliding1"})
+
+	// каждое чтение продлевает жизнь записи
+	for i := 0; i < 3; i++ {
+		clock.Advance(40 * time.Second)
+		if _, found := cache.Get("sliding1"); !found {
+			t.Fatalf("Запись истекла, хотя читалась каждые 40 секунд (итерация %d)", i)
+		}
+	}
+
+	// без чтений запись истекает
+	clock.Advance(61 * time.Second)
+	if _, found := cache.Get("sliding1"); found {
+		t.Error("Запись не истекла после TTL без чтений")
+	}
+}
+
+func TestCacheTTLJitter(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	cache := NewOrderCacheWithOptions(OrderCacheOptions{
+		MaxSize:   100,
+		TTL:       time.Minute,
+		TTLJitter: time.Minute,
+		Clock:     clock,
+	})
+	defer cache.Stop()
+
+	for i := 0; i < 50; i++ {
+		cache.Set(database.Order{OrderUID: fmt.Sprintf("jitter%d", i)})
+	}
+
+	// записи, добавленные одновременно, истекают в разное время в пределах [TTL, TTL+jitter)
+	expirations := make(map[time.Time]bool)
+	cache.Range(func(key string, cached CachedOrder) bool {
+		expiresAt := cached.ExpiresAt
+		if ttl := expiresAt.Sub(clock.Now()); ttl < time.Minute || ttl >= 2*time.Minute {
+			t.Errorf("TTL записи %s вне диапазона: %v", key, ttl)
+		}
+		expirations[expiresAt] = true
+		return true
+	})
+	if len(expirations) < 2 {
+		t.Error("Все записи истекают одновременно")
+	}
+}
+
+func TestCacheRefreshAhead(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	var loads atomic.Int32
+	cache := NewOrderCacheWithOptions(OrderCacheOptions{
+		MaxSize:      10,
+		TTL:          time.Minute,
+		RefreshAhead: 10 * time.Second,
+		Clock:        clock,
+		Loader: func(orderUID string) (database.Order, error) {
+			loads.Add(1)
+			return database.Order{OrderUID: orderUID, TrackNumber: "REFRESHED"}, nil
+		},
+	})
+	defer cache.Stop()
+
+	cache.Set(database.Order{OrderUID: "refresh1", TrackNumber: "ORIGINAL"})
+
+	// до порога обновления загрузки нет
+	clock.Advance(30 * time.Second)
+	cache.Get("refresh1")
+	if loads.Load() != 0 {
+		t.Errorf("Запись обновлена раньше порога")
+	}
+
+	// близко к истечению чтение запускает фоновое обновление, старое значение отдается сразу
+	clock.Advance(25 * time.Second)
+	if order, found := cache.Get("refresh1"); !found || order.TrackNumber != "ORIGINAL" {
+		t.Errorf("Ожидалось старое значение до обновления, получено %+v", order)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		order, _ := cache.Get("refresh1")
+		if order.TrackNumber == "REFRESHED" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Запись не обновилась в фоне")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// обновленная запись живет полный TTL
+	clock.Advance(50 * time.Second)
+	if _, found := cache.Get("refresh1"); !found {
+		t.Error("Обновленная запись истекла раньше TTL")
+	}
+	if loads.Load() != 1 {
+		t.Errorf("Ожидалась 1 фоновая загрузка, выполнено %d", loads.Load())
+	}
+}
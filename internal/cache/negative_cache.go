@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"order-service/internal/ttlcache"
+	"time"
+)
+
+// реализация интерфейса NegativeCache
+type OrderNegativeCache struct {
+	entries *ttlcache.Cache[string, struct{}]
+	maxSize int
+}
+
+// создаем кэш отсутствующих заказов
+func NewNegativeCache(maxSize int, ttl time.Duration) NegativeCache {
+	return &OrderNegativeCache{
+		entries: ttlcache.New(ttlcache.Options[string, struct{}]{
+			MaxSize: maxSize,
+			TTL:     ttl,
+		}),
+		maxSize: maxSize,
+	}
+}
+
+// запоминает, что заказа нет в БД
+func (nc *OrderNegativeCache) Add(orderUID string) {
+	if nc.maxSize <= 0 {
+		return
+	}
+	nc.entries.Set(orderUID, struct{}{})
+}
+
+// проверяет, известно ли что заказа нет в БД
+func (nc *OrderNegativeCache) Contains(orderUID string) bool {
+	_, found := nc.entries.Get(orderUID)
+	return found
+}
+
+// удаляет запись, например когда заказ появился в БД
+func (nc *OrderNegativeCache) Remove(orderUID string) {
+	nc.entries.Delete(orderUID)
+}
+
+// забывает все отсутствующие заказы
+func (nc *OrderNegativeCache) Clear() {
+	nc.entries.Clear()
+}
+
+// возвращает количество записей
+func (nc *OrderNegativeCache) Size() int {
+	return nc.entries.Len()
+}
@@ -0,0 +1,306 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"order-service/internal/database"
+	"order-service/internal/ttlcache"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// параметры подключения к Redis
+type RedisOptions struct {
+	Addr          string
+	Password      string
+	DB            int
+	KeyPrefix     string
+	TTL           time.Duration
+	Timeout       time.Duration
+	RetryInterval time.Duration
+}
+
+// реализация интерфейса Cache поверх Redis, используется как общий L2
+type RedisCache struct {
+	client        *redis.Client
+	prefix        string
+	ttl           time.Duration
+	timeout       time.Duration
+	retryInterval time.Duration
+	downUntil     atomic.Int64
+	stats         ttlcache.Counters
+}
+
+// создаем кэш в Redis
+func NewRedisCache(opts RedisOptions) Cache {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 200 * time.Millisecond
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:         opts.Addr,
+		Password:     opts.Password,
+		DB:           opts.DB,
+		DialTimeout:  opts.Timeout,
+		ReadTimeout:  opts.Timeout,
+		WriteTimeout: opts.Timeout,
+		MaxRetries:   -1,
+	})
+
+	return &RedisCache{
+		client:        client,
+		prefix:        opts.KeyPrefix,
+		ttl:           opts.TTL,
+		timeout:       opts.Timeout,
+		retryInterval: opts.RetryInterval,
+	}
+}
+
+// возвращает заказ из Redis, при недоступности Redis - промах
+func (rc *RedisCache) Get(orderUID string) (database.Order, bool) {
+	order, found := rc.Peek(orderUID)
+	if found {
+		rc.stats.RecordHit()
+	} else {
+		rc.stats.RecordMiss()
+	}
+	return order, found
+}
+
+// читает заказ без учета в статистике; GET не продлевает TTL ключа
+func (rc *RedisCache) Peek(orderUID string) (database.Order, bool) {
+	if !rc.available() {
+		return database.Order{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rc.timeout)
+	defer cancel()
+
+	data, err := rc.client.Get(ctx, rc.key(orderUID)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			rc.markDown(err)
+		}
+		return database.Order{}, false
+	}
+
+	var order database.Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		log.Printf("Поврежденная запись в Redis %s: %v", orderUID, err)
+		return database.Order{}, false
+	}
+	return order, true
+}
+
+// добавляет заказ в Redis
+func (rc *RedisCache) Set(order database.Order) {
+	if !rc.available() {
+		return
+	}
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		log.Printf("Ошибка сериализации заказа %s: %v", order.OrderUID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rc.timeout)
+	defer cancel()
+
+	if err := rc.client.Set(ctx, rc.key(order.OrderUID), data, rc.ttl).Err(); err != nil {
+		rc.markDown(err)
+	}
+}
+
+// удаляет заказ из Redis
+func (rc *RedisCache) Delete(orderUID string) bool {
+	if !rc.available() {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rc.timeout)
+	defer cancel()
+
+	removed, err := rc.client.Del(ctx, rc.key(orderUID)).Result()
+	if err != nil {
+		rc.markDown(err)
+		return false
+	}
+	if removed > 0 {
+		rc.stats.RecordEviction(ttlcache.EvictionDeleted)
+	}
+	return removed > 0
+}
+
+// удаляет все ключи с префиксом кэша пачками по результатам SCAN
+func (rc *RedisCache) Flush() int {
+	removed := 0
+	rc.scan(func(keys []string) bool {
+		ctx, cancel := context.WithTimeout(context.Background(), rc.timeout)
+		defer cancel()
+
+		count, err := rc.client.Del(ctx, keys...).Result()
+		if err != nil {
+			rc.markDown(err)
+			return false
+		}
+		for range count {
+			rc.stats.RecordEviction(ttlcache.EvictionDeleted)
+		}
+		removed += int(count)
+		return true
+	})
+	return removed
+}
+
+// возвращает количество заказов в Redis с нашим префиксом
+func (rc *RedisCache) Size() int {
+	count := 0
+	rc.scan(func(keys []string) bool {
+		count += len(keys)
+		return true
+	})
+	return count
+}
+
+// устаревшие записи Redis удаляет сам по TTL
+func (rc *RedisCache) Cleanup(ttl time.Duration) {}
+
+// закрывает соединение с Redis
+func (rc *RedisCache) Stop() {
+	if err := rc.client.Close(); err != nil {
+		log.Printf("Ошибка закрытия Redis: %v", err)
+	}
+}
+
+// итерируется по заказам в Redis
+func (rc *RedisCache) Range(f func(orderUID string, cached CachedOrder) bool) {
+	rc.scan(func(keys []string) bool {
+		return rc.getMany(keys, f)
+	})
+}
+
+// время добавления Redis не хранит, поэтому страница упорядочена по order_uid;
+// загружаются только заказы страницы
+func (rc *RedisCache) Entries(offset, limit int) ([]CachedOrder, int) {
+	var keys []string
+	rc.scan(func(batch []string) bool {
+		keys = append(keys, batch...)
+		return true
+	})
+	sort.Strings(keys)
+
+	total := len(keys)
+	if offset >= total {
+		return []CachedOrder{}, total
+	}
+	keys = keys[offset:]
+	if limit > 0 && limit < len(keys) {
+		keys = keys[:limit]
+	}
+
+	entries := []CachedOrder{}
+	rc.getMany(keys, func(orderUID string, cached CachedOrder) bool {
+		entries = append(entries, cached)
+		return true
+	})
+	return entries, total
+}
+
+// вторичные индексы ведет только локальный кэш
+func (rc *RedisCache) GetByTrackNumber(trackNumber string) []database.Order {
+	return nil
+}
+
+func (rc *RedisCache) GetByCustomerID(customerID string) []database.Order {
+	return nil
+}
+
+// возвращает статистику Redis кэша; размер не заполняется, так как его подсчет
+// сканирует все ключи с префиксом в общем Redis, а статистику может запросить любой читатель
+func (rc *RedisCache) Stats() CacheStats {
+	return newCacheStats(rc.stats.Snapshot(0))
+}
+
+// учитывает загрузку заказа из БД
+func (rc *RedisCache) RecordLoad(duration time.Duration, err error) {
+	rc.stats.RecordLoad(duration, err)
+}
+
+// Вспомогательные методы
+func (rc *RedisCache) key(orderUID string) string {
+	return rc.prefix + orderUID
+}
+
+// читает заказы по ключам одним MGET; отсутствующие и поврежденные пропускаются
+func (rc *RedisCache) getMany(keys []string, f func(orderUID string, cached CachedOrder) bool) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), rc.timeout)
+	defer cancel()
+
+	values, err := rc.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		rc.markDown(err)
+		return false
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var order database.Order
+		if err := json.Unmarshal([]byte(data), &order); err != nil {
+			continue
+		}
+		if !f(keys[i][len(rc.prefix):], CachedOrder{Order: order}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (rc *RedisCache) scan(f func(keys []string) bool) {
+	if !rc.available() {
+		return
+	}
+
+	var cursor uint64
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), rc.timeout)
+		keys, next, err := rc.client.Scan(ctx, cursor, rc.prefix+"*", 500).Result()
+		cancel()
+		if err != nil {
+			rc.markDown(err)
+			return
+		}
+
+		if len(keys) > 0 && !f(keys) {
+			return
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return
+		}
+	}
+}
+
+// после ошибки не обращаемся к Redis retryInterval, чтобы не замедлять запросы
+func (rc *RedisCache) available() bool {
+	return time.Now().UnixNano() >= rc.downUntil.Load()
+}
+
+func (rc *RedisCache) markDown(err error) {
+	until := time.Now().Add(rc.retryInterval).UnixNano()
+	if previous := rc.downUntil.Swap(until); previous < time.Now().UnixNano() {
+		log.Printf("Redis недоступен, L2 кэш отключен на %s: %v", rc.retryInterval, err)
+	}
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"order-service/internal/database"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedisCache(mr *miniredis.Miniredis) Cache {
+	return NewRedisCache(RedisOptions{
+		Addr:          mr.Addr(),
+		KeyPrefix:     "order:",
+		TTL:           time.Minute,
+		Timeout:       100 * time.Millisecond,
+		RetryInterval: 50 * time.Millisecond,
+	})
+}
+
+func TestRedisCacheBasicOperations(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisCache := newTestRedisCache(mr)
+	defer redisCache.Stop()
+
+	redisCache.Set(database.Order{OrderUID: "redis1", Items: []database.Item{{Name: "Mascaras"}}})
+
+	order, found := redisCache.Get("redis1")
+	if !found {
+		t.Fatal("Заказ не найден в Redis")
+	}
+	if len(order.Items) != 1 || order.Items[0].Name != "Mascaras" {
+		t.Errorf("Заказ из Redis десериализован неверно: %+v", order)
+	}
+	if redisCache.Size() != 1 {
+		t.Errorf("Ожидался размер 1, получен %d", redisCache.Size())
+	}
+
+	// запись истекает по TTL Redis
+	mr.FastForward(2 * time.Minute)
+	if _, found := redisCache.Get("redis1"); found {
+		t.Error("Заказ все еще в Redis после истечения TTL")
+	}
+}
+
+func TestTieredCacheSharesL2(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	// две реплики с собственными L1 и общим L2
+	replica1 := NewTieredCache(NewOrderCache(10, time.Minute), newTestRedisCache(mr))
+	defer replica1.Stop()
+	replica2 := NewTieredCache(NewOrderCache(10, time.Minute), newTestRedisCache(mr))
+	defer replica2.Stop()
+
+	replica1.Set(database.Order{OrderUID: "shared1", TrackNumber: "TRACK"})
+
+	order, found := replica2.Get("shared1")
+	if !found || order.TrackNumber != "TRACK" {
+		t.Fatalf("Заказ не найден во второй реплике через L2: %+v", order)
+	}
+
+	// после чтения из L2 заказ попадает в L1 второй реплики
+	if replica2.Size() != 1 {
+		t.Errorf("Ожидался размер L1 1, получен %d", replica2.Size())
+	}
+	if stats := replica2.Stats(); stats.L2 == nil || stats.L2.Hits != 1 {
+		t.Errorf("Неверная статистика L2: %+v", stats.L2)
+	}
+
+	// очистка затрагивает L2, иначе заказ вернулся бы в L1 при следующем чтении
+	replica1.Set(database.Order{OrderUID: "shared2"})
+	mr.Set("other:key", "value")
+	if flushed := replica1.Flush(); flushed != 2 {
+		t.Errorf("Ожидалась очистка 2 записей, очищено %d", flushed)
+	}
+	if _, found := replica1.Get("shared2"); found {
+		t.Error("Заказ вернулся из L2 после очистки")
+	}
+	if !mr.Exists("other:key") {
+		t.Error("Очистка удалила ключ без префикса кэша")
+	}
+
+	replica1.Delete("shared1")
+	replica2.Delete("shared1")
+	if _, found := replica2.Get("shared1"); found {
+		t.Error("Заказ все еще в кэше после удаления")
+	}
+}
+
+func TestTieredCacheRedisDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	tiered := NewTieredCache(NewOrderCache(10, time.Minute), newTestRedisCache(mr))
+	defer tiered.Stop()
+
+	mr.Close()
+
+	// при недоступном Redis кэш продолжает работать на L1
+	tiered.Set(database.Order{OrderUID: "local1"})
+	if _, found := tiered.Get("local1"); !found {
+		t.Error("Заказ не найден в L1 при недоступном Redis")
+	}
+
+	start := time.Now()
+	if _, found := tiered.Get("missing"); found {
+		t.Error("Найден несуществующий заказ")
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Промах при недоступном Redis занял %v", elapsed)
+	}
+}
@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"order-service/internal/database"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// формат файла: магическая строка, версия, длина данных, данные gob, crc32 данных
+const (
+	snapshotMagic   = "OCSNAP"
+	snapshotVersion = uint16(1)
+)
+
+var (
+	ErrSnapshotStale   = errors.New("снимок кэша устарел")
+	ErrSnapshotCorrupt = errors.New("снимок кэша поврежден")
+)
+
+// содержимое снимка кэша
+type cacheSnapshot struct {
+	CreatedAt time.Time
+	Orders    []database.Order
+}
+
+// сохраняет содержимое кэша в файл
+func SaveCacheSnapshot(cache Cache, path string) error {
+	snapshot := cacheSnapshot{CreatedAt: time.Now()}
+	cache.Range(func(orderUID string, cached CachedOrder) bool {
+		snapshot.Orders = append(snapshot.Orders, cached.Order)
+		return true
+	})
+
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(snapshot); err != nil {
+		return fmt.Errorf("ошибка кодирования снимка: %v", err)
+	}
+
+	// пишем во временный файл и переименовываем, чтобы не оставить обрезанный снимок
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла снимка: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	w.WriteString(snapshotMagic)
+	binary.Write(w, binary.BigEndian, snapshotVersion)
+	binary.Write(w, binary.BigEndian, uint64(payload.Len()))
+	w.Write(payload.Bytes())
+	binary.Write(w, binary.BigEndian, crc32.ChecksumIEEE(payload.Bytes()))
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ошибка записи снимка: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ошибка записи снимка: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("ошибка записи снимка: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("ошибка сохранения снимка: %v", err)
+	}
+
+	log.Printf("Снимок кэша сохранен: %s, заказов: %d", path, len(snapshot.Orders))
+	return nil
+}
+
+// загружает кэш из файла, если снимок не старше maxAge
+func RestoreCacheFromSnapshot(cache Cache, path string, maxAge time.Duration) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	snapshot, err := readSnapshot(bufio.NewReader(file))
+	if err != nil {
+		return 0, err
+	}
+
+	if age := time.Since(snapshot.CreatedAt); age > maxAge {
+		return 0, fmt.Errorf("%w: возраст %s", ErrSnapshotStale, age.Round(time.Second))
+	}
+
+	for _, order := range snapshot.Orders {
+		cache.Set(order)
+	}
+
+	log.Printf("Кэш восстановлен из снимка %s, заказов: %d", path, len(snapshot.Orders))
+	return len(snapshot.Orders), nil
+}
+
+func readSnapshot(r io.Reader) (cacheSnapshot, error) {
+	var snapshot cacheSnapshot
+
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != snapshotMagic {
+		return snapshot, fmt.Errorf("%w: неизвестный формат", ErrSnapshotCorrupt)
+	}
+
+	var version uint16
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return snapshot, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	if version != snapshotVersion {
+		return snapshot, fmt.Errorf("%w: неподдерживаемая версия %d", ErrSnapshotCorrupt, version)
+	}
+
+	var length uint64
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return snapshot, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	payload := make([]byte, 0, min(length, 64<<20))
+	buf := bytes.NewBuffer(payload)
+	if _, err := io.CopyN(buf, r, int64(length)); err != nil {
+		return snapshot, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	var checksum uint32
+	if err := binary.Read(r, binary.BigEndian, &checksum); err != nil {
+		return snapshot, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	if crc32.ChecksumIEEE(buf.Bytes()) != checksum {
+		return snapshot, fmt.Errorf("%w: неверная контрольная сумма", ErrSnapshotCorrupt)
+	}
+
+	if err := gob.NewDecoder(buf).Decode(&snapshot); err != nil {
+		return snapshot, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	return snapshot, nil
+}
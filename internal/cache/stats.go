@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"order-service/internal/ttlcache"
+	"time"
+)
+
+// статистика вытеснений по причинам
+type EvictionStats = ttlcache.EvictionStats
+
+// статистика работы кэша
+type CacheStats struct {
+	Size        int           `json:"size"`
+	Hits        int64         `json:"hits"`
+	Misses      int64         `json:"misses"`
+	HitRatio    float64       `json:"hit_ratio"`
+	Expirations int64         `json:"expirations"`
+	Evictions   EvictionStats `json:"evictions"`
+	Loads       int64         `json:"loads"`
+	LoadErrors  int64         `json:"load_errors"`
+	LoadTimeAvg time.Duration `json:"load_time_avg_ns"`
+	LoadTimeMax time.Duration `json:"load_time_max_ns"`
+	Refreshes   int64         `json:"refreshes"`
+	L2          *CacheStats   `json:"l2,omitempty"` // Redis, без размера
+}
+
+func newCacheStats(stats ttlcache.Stats) CacheStats {
+	return CacheStats{
+		Size:        stats.Size,
+		Hits:        stats.Hits,
+		Misses:      stats.Misses,
+		HitRatio:    stats.HitRatio,
+		Expirations: stats.Expirations,
+		Evictions:   stats.Evictions,
+		Loads:       stats.Loads,
+		LoadErrors:  stats.LoadErrors,
+		LoadTimeAvg: stats.LoadTimeAvg,
+		LoadTimeMax: stats.LoadTimeMax,
+		Refreshes:   stats.Refreshes,
+	}
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"order-service/internal/database"
+	"time"
+)
+
+// реализация интерфейса Cache из двух уровней: локальный L1 и общий для реплик L2
+type TieredCache struct {
+	l1 Cache
+	l2 Cache
+}
+
+// создаем двухуровневый кэш
+func NewTieredCache(l1, l2 Cache) Cache {
+	return &TieredCache{l1: l1, l2: l2}
+}
+
+// ищет заказ сначала в L1, затем в L2; найденное в L2 поднимается в L1
+func (tc *TieredCache) Get(orderUID string) (database.Order, bool) {
+	if order, found := tc.l1.Get(orderUID); found {
+		return order, true
+	}
+
+	order, found := tc.l2.Get(orderUID)
+	if found {
+		tc.l1.Set(order)
+	}
+	return order, found
+}
+
+// ищет заказ в L1, затем в L2, не поднимая его в L1 и не затрагивая статистику
+func (tc *TieredCache) Peek(orderUID string) (database.Order, bool) {
+	if order, found := tc.l1.Peek(orderUID); found {
+		return order, true
+	}
+	return tc.l2.Peek(orderUID)
+}
+
+// добавляет заказ в оба уровня
+func (tc *TieredCache) Set(order database.Order) {
+	tc.l1.Set(order)
+	tc.l2.Set(order)
+}
+
+// удаляет заказ из обоих уровней
+func (tc *TieredCache) Delete(orderUID string) bool {
+	inL1 := tc.l1.Delete(orderUID)
+	inL2 := tc.l2.Delete(orderUID)
+	return inL1 || inL2
+}
+
+// очищает оба уровня, иначе записи L2 вернутся в L1 при следующем чтении;
+// возвращает количество удаленных заказов в большем из уровней
+func (tc *TieredCache) Flush() int {
+	return max(tc.l1.Flush(), tc.l2.Flush())
+}
+
+// возвращает размер L1
+func (tc *TieredCache) Size() int {
+	return tc.l1.Size()
+}
+
+// очищает устаревшие записи L1, L2 очищается по своему TTL
+func (tc *TieredCache) Cleanup(ttl time.Duration) {
+	tc.l1.Cleanup(ttl)
+}
+
+// останавливает оба уровня
+func (tc *TieredCache) Stop() {
+	tc.l1.Stop()
+	tc.l2.Stop()
+}
+
+// итерируется по элементам L1
+func (tc *TieredCache) Range(f func(orderUID string, cached CachedOrder) bool) {
+	tc.l1.Range(f)
+}
+
+// страница записей только L1: общий L2 содержит заказы всех реплик
+// и не хранит время добавления
+func (tc *TieredCache) Entries(offset, limit int) ([]CachedOrder, int) {
+	return tc.l1.Entries(offset, limit)
+}
+
+// ищет по вторичному индексу L1
+func (tc *TieredCache) GetByTrackNumber(trackNumber string) []database.Order {
+	return tc.l1.GetByTrackNumber(trackNumber)
+}
+
+func (tc *TieredCache) GetByCustomerID(customerID string) []database.Order {
+	return tc.l1.GetByCustomerID(customerID)
+}
+
+// возвращает статистику L1 вместе со статистикой L2
+func (tc *TieredCache) Stats() CacheStats {
+	stats := tc.l1.Stats()
+	l2Stats := tc.l2.Stats()
+	stats.L2 = &l2Stats
+	return stats
+}
+
+// учитывает загрузку заказа из БД
+func (tc *TieredCache) RecordLoad(duration time.Duration, err error) {
+	tc.l1.RecordLoad(duration, err)
+}
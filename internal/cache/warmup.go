@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"order-service/internal/database"
+	"sync"
+	"time"
+)
+
+// состояния прогрева кэша
+const (
+	WarmupPending   = "pending"
+	WarmupRunning   = "running"
+	WarmupCompleted = "completed"
+	WarmupFailed    = "failed"
+	WarmupCancelled = "cancelled"
+)
+
+// параметры прогрева кэша
+type WarmupOptions struct {
+	Limit          int
+	BatchSize      int
+	SnapshotPath   string
+	SnapshotMaxAge time.Duration
+}
+
+// состояние прогрева кэша
+type WarmupProgress struct {
+	State      string    `json:"state"`
+	Source     string    `json:"source,omitempty"`
+	Loaded     int       `json:"loaded"`
+	Limit      int       `json:"limit"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// завершен ли прогрев, успешно или нет
+func (p WarmupProgress) Done() bool {
+	return p.State != WarmupPending && p.State != WarmupRunning
+}
+
+// реализация интерфейса CacheRestorer
+type CacheWarmer struct {
+	repo     database.OrderRepository
+	cache    Cache
+	opts     WarmupOptions
+	mutex    sync.RWMutex
+	progress WarmupProgress
+}
+
+// создает прогрев кэша: из снимка, а при его отсутствии - постранично из БД
+func NewCacheWarmer(repo database.OrderRepository, cache Cache, opts WarmupOptions) *CacheWarmer {
+	return &CacheWarmer{
+		repo:     repo,
+		cache:    cache,
+		opts:     opts,
+		progress: WarmupProgress{State: WarmupPending, Limit: opts.Limit},
+	}
+}
+
+// выполняет прогрев, блокируется до завершения или отмены контекста
+func (w *CacheWarmer) Run(ctx context.Context) error {
+	w.update(func(p *WarmupProgress) {
+		p.State = WarmupRunning
+		p.StartedAt = time.Now()
+	})
+
+	if w.opts.SnapshotPath != "" {
+		count, err := RestoreCacheFromSnapshot(w.cache, w.opts.SnapshotPath, w.opts.SnapshotMaxAge)
+		if err == nil {
+			w.finish("snapshot", count, nil)
+			return nil
+		}
+		log.Printf("Снимок кэша не использован: %v", err)
+	}
+
+	log.Printf("Восстановление кэша из БД, лимит: %d", w.opts.Limit)
+	w.update(func(p *WarmupProgress) { p.Source = "database" })
+
+	err := w.repo.StreamOrders(ctx, w.opts.Limit, w.opts.BatchSize, func(orders []database.Order) error {
+		for _, order := range orders {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			w.cache.Set(order)
+		}
+		w.update(func(p *WarmupProgress) { p.Loaded += len(orders) })
+		return nil
+	})
+
+	progress := w.Progress()
+	w.finish("database", progress.Loaded, err)
+	if err != nil {
+		return fmt.Errorf("ошибка восстановления кэша: %w", err)
+	}
+
+	log.Printf("Успешно загружено %d заказов в кэш", progress.Loaded)
+	return nil
+}
+
+// возвращает текущее состояние прогрева
+func (w *CacheWarmer) Progress() WarmupProgress {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.progress
+}
+
+func (w *CacheWarmer) update(f func(p *WarmupProgress)) {
+	w.mutex.Lock()
+	f(&w.progress)
+	w.mutex.Unlock()
+}
+
+func (w *CacheWarmer) finish(source string, loaded int, err error) {
+	w.update(func(p *WarmupProgress) {
+		p.Source = source
+		p.Loaded = loaded
+		p.FinishedAt = time.Now()
+		switch {
+		case err == nil:
+			p.State = WarmupCompleted
+		case errors.Is(err, context.Canceled):
+			p.State = WarmupCancelled
+		default:
+			p.State = WarmupFailed
+			p.Error = err.Error()
+		}
+	})
+}
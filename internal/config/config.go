@@ -3,14 +3,19 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	DB    DatabaseConfig
-	Kafka KafkaConfig
-	HTTP  HTTPConfig
-	Cache CacheConfig
+	DB      DatabaseConfig
+	Kafka   KafkaConfig
+	HTTP    HTTPConfig
+	Cache   CacheConfig
+	Redis   RedisConfig
+	Webhook WebhookConfig
+	Auth    AuthConfig
+	PII     PIIConfig
 }
 
 type DatabaseConfig struct {
@@ -30,11 +35,73 @@ type KafkaConfig struct {
 
 type HTTPConfig struct {
 	Port string
+	// устаревший общий токен, принимается как API ключ с ролью admin
+	AdminToken string
+	// событий в буфере клиента живой ленты
+	FeedBuffer int
+	// источники, которым разрешены кросс-доменные запросы; пусто - CORS отключен
+	CORSOrigins []string
+}
+
+// маскирование персональных данных покупателя в логах и ответах API
+type PIIConfig struct {
+	// список "поле=правило" через запятую поверх правил по умолчанию
+	MaskRules string
+	// клиенты с этой ролью и выше получают данные без маски
+	UnmaskedRole string
+}
+
+// аутентификация клиентов HTTP API
+type AuthConfig struct {
+	// список "имя:роль:sha256" через запятую
+	APIKeys string
+	// искать ключи в таблице api_keys
+	DBKeys      bool
+	KeyCacheTTL time.Duration
+	// пустые секрет и путь к ключу отключают JWT
+	JWTSecret        string
+	JWTPublicKeyFile string
+	JWTIssuer        string
+	JWTAudience      string
+	JWTRoleClaim     string
+	JWTLeeway        time.Duration
+	// роль запросов без учетных данных, пусто - требуется аутентификация
+	AnonymousRole string
 }
 type CacheConfig struct {
-	MaxSize      int
-	RestoreLimit int
-	TTL          time.Duration
+	MaxSize          int
+	RestoreLimit     int
+	RestoreBatchSize int
+	TTL              time.Duration
+	NegativeMaxSize  int
+	NegativeTTL      time.Duration
+	SnapshotPath     string
+	SnapshotMaxAge   time.Duration
+	Sliding          bool
+	TTLJitter        time.Duration
+	RefreshAhead     time.Duration
+}
+
+// пустой Addr отключает L2 кэш в Redis
+type RedisConfig struct {
+	Addr          string
+	Password      string
+	DB            int
+	KeyPrefix     string
+	TTL           time.Duration
+	Timeout       time.Duration
+	RetryInterval time.Duration
+}
+
+// доставка уведомлений партнерам о новых заказах
+type WebhookConfig struct {
+	Enabled      bool
+	Workers      int
+	MaxAttempts  int
+	Timeout      time.Duration
+	PollInterval time.Duration
+	RetryBase    time.Duration
+	RetryMax     time.Duration
 }
 
 func LoadConfig() Config {
@@ -53,12 +120,57 @@ func LoadConfig() Config {
 			GroupID: getEnv("KAFKA_GROUP_ID", "order-service-group"),
 		},
 		HTTP: HTTPConfig{
-			Port: getEnv("HTTP_PORT", ":8080"),
+			Port:        getEnv("HTTP_PORT", ":8080"),
+			AdminToken:  getEnv("HTTP_ADMIN_TOKEN", ""),
+			FeedBuffer:  getEnvAsInt("HTTP_FEED_BUFFER", 64),
+			CORSOrigins: getEnvAsList("HTTP_CORS_ORIGINS"),
 		},
 		Cache: CacheConfig{
-			MaxSize:      getEnvAsInt("CACHE_MAX_SIZE", 100),
-			RestoreLimit: getEnvAsInt("CACHE_RESTORE_LIMIT", 100),
-			TTL:          getEnvAsDuration("CACHE_TTL", 60*time.Minute),
+			MaxSize:          getEnvAsInt("CACHE_MAX_SIZE", 100),
+			RestoreLimit:     getEnvAsInt("CACHE_RESTORE_LIMIT", 100),
+			RestoreBatchSize: getEnvAsInt("CACHE_RESTORE_BATCH_SIZE", 500),
+			TTL:              getEnvAsDuration("CACHE_TTL", 60*time.Minute),
+			NegativeMaxSize:  getEnvAsInt("CACHE_NEGATIVE_MAX_SIZE", 1000),
+			NegativeTTL:      getEnvAsDuration("CACHE_NEGATIVE_TTL", 30*time.Second),
+			SnapshotPath:     getEnv("CACHE_SNAPSHOT_PATH", ""),
+			SnapshotMaxAge:   getEnvAsDuration("CACHE_SNAPSHOT_MAX_AGE", 10*time.Minute),
+			Sliding:          getEnvAsBool("CACHE_SLIDING_EXPIRATION", false),
+			TTLJitter:        getEnvAsDuration("CACHE_TTL_JITTER", 0),
+			RefreshAhead:     getEnvAsDuration("CACHE_REFRESH_AHEAD", 0),
+		},
+		Redis: RedisConfig{
+			Addr:          getEnv("REDIS_ADDR", ""),
+			Password:      getEnv("REDIS_PASSWORD", ""),
+			DB:            getEnvAsInt("REDIS_DB", 0),
+			KeyPrefix:     getEnv("REDIS_KEY_PREFIX", "order:"),
+			TTL:           getEnvAsDuration("REDIS_TTL", 24*time.Hour),
+			Timeout:       getEnvAsDuration("REDIS_TIMEOUT", 200*time.Millisecond),
+			RetryInterval: getEnvAsDuration("REDIS_RETRY_INTERVAL", 5*time.Second),
+		},
+		Webhook: WebhookConfig{
+			Enabled:      getEnvAsBool("WEBHOOKS_ENABLED", true),
+			Workers:      getEnvAsInt("WEBHOOK_WORKERS", 4),
+			MaxAttempts:  getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+			Timeout:      getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			PollInterval: getEnvAsDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+			RetryBase:    getEnvAsDuration("WEBHOOK_RETRY_BASE", 10*time.Second),
+			RetryMax:     getEnvAsDuration("WEBHOOK_RETRY_MAX", time.Hour),
+		},
+		Auth: AuthConfig{
+			APIKeys:          getEnv("AUTH_API_KEYS", ""),
+			DBKeys:           getEnvAsBool("AUTH_DB_KEYS", false),
+			KeyCacheTTL:      getEnvAsDuration("AUTH_KEY_CACHE_TTL", time.Minute),
+			JWTSecret:        getEnv("AUTH_JWT_SECRET", ""),
+			JWTPublicKeyFile: getEnv("AUTH_JWT_PUBLIC_KEY_FILE", ""),
+			JWTIssuer:        getEnv("AUTH_JWT_ISSUER", ""),
+			JWTAudience:      getEnv("AUTH_JWT_AUDIENCE", ""),
+			JWTRoleClaim:     getEnv("AUTH_JWT_ROLE_CLAIM", "role"),
+			JWTLeeway:        getEnvAsDuration("AUTH_JWT_LEEWAY", 30*time.Second),
+			AnonymousRole:    getEnv("AUTH_ANONYMOUS_ROLE", ""),
+		},
+		PII: PIIConfig{
+			MaskRules:    getEnv("PII_MASK_RULES", ""),
+			UnmaskedRole: getEnv("PII_UNMASKED_ROLE", "support"),
 		},
 	}
 }
@@ -79,6 +191,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		// Парсим из строки (например:"60m")
@@ -92,3 +213,14 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+// значения через запятую без пустых элементов
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
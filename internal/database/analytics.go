@@ -0,0 +1,218 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// интервалы группировки аналитики, передаются в date_trunc
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// дополнительные измерения группировки и соответствующие им колонки
+var analyticsDimensions = map[string]string{
+	"currency":         "p.currency",
+	"provider":         "p.provider",
+	"bank":             "p.bank",
+	"delivery_service": "o.delivery_service",
+	"region":           "d.region",
+}
+
+// параметры аналитических запросов; нулевые From/To не ограничивают период
+type AnalyticsFilter struct {
+	Interval string
+	// пустое значение - без группировки по измерению
+	Dimension string
+	From      time.Time
+	To        time.Time
+}
+
+// показатели продаж за период
+type SalesPoint struct {
+	Period    time.Time `json:"period"`
+	Dimension string    `json:"dimension,omitempty"`
+	Orders    int64     `json:"orders"`
+	// суммы складываются без конвертации валют, для смешанных валют группируйте по currency
+	Revenue           int64   `json:"revenue"`
+	AvgBasket         float64 `json:"avg_basket"`
+	DeliveryCost      int64   `json:"delivery_cost"`
+	DeliveryCostShare float64 `json:"delivery_cost_share"`
+}
+
+// показатели продаж бренда или товара; Period и Dimension заполнены при группировке
+type TopEntry struct {
+	Period    time.Time `json:"period,omitzero"`
+	Dimension string    `json:"dimension,omitempty"`
+	Brand     string    `json:"brand"`
+	NmID      int       `json:"nm_id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Items     int64     `json:"items"`
+	Orders    int64     `json:"orders"`
+	Revenue   int64     `json:"revenue"`
+}
+
+// добавляет условия периода по дате создания заказа
+func periodConditions(filter AnalyticsFilter, args []interface{}) (string, []interface{}) {
+	var conditions []string
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("o.date_created >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("o.date_created < $%d", len(args)))
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// проверяет, что интервал группировки - day, week или month
+func validateInterval(interval string) error {
+	switch interval {
+	case IntervalDay, IntervalWeek, IntervalMonth:
+		return nil
+	default:
+		return fmt.Errorf("%w: interval должен быть day, week или month", ErrInvalidArgument)
+	}
+}
+
+// возвращает выражение измерения для SELECT, без измерения - пустую строку
+func analyticsDimension(dimension string) (string, error) {
+	if dimension == "" {
+		return "''", nil
+	}
+	column, ok := analyticsDimensions[dimension]
+	if !ok {
+		return "", fmt.Errorf("%w: неизвестное измерение %s", ErrInvalidArgument, dimension)
+	}
+	return "COALESCE(" + column + ", '')", nil
+}
+
+// считает количество заказов, выручку, средний чек и долю доставки по периодам
+func (r *OrderRepositoryImpl) GetSalesStats(ctx context.Context, filter AnalyticsFilter) ([]SalesPoint, error) {
+	if err := validateInterval(filter.Interval); err != nil {
+		return nil, err
+	}
+	dimension, err := analyticsDimension(filter.Dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := periodConditions(filter, []interface{}{filter.Interval})
+	query := fmt.Sprintf(`
+		SELECT date_trunc($1, o.date_created) AS period, %s AS dimension,
+		       count(*), COALESCE(sum(p.amount), 0), COALESCE(avg(p.amount), 0),
+		       COALESCE(sum(p.delivery_cost), 0)
+		FROM orders o
+		LEFT JOIN delivery d ON o.order_uid = d.order_uid
+		LEFT JOIN payment p ON o.order_uid = p.order_uid
+		%s
+		GROUP BY 1, 2
+		ORDER BY 1, 2`, dimension, where)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса статистики продаж: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	points := []SalesPoint{}
+	for rows.Next() {
+		var point SalesPoint
+		if err := rows.Scan(&point.Period, &point.Dimension, &point.Orders, &point.Revenue,
+			&point.AvgBasket, &point.DeliveryCost); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования статистики продаж: %w", classifyError(err))
+		}
+		if point.Revenue > 0 {
+			point.DeliveryCostShare = float64(point.DeliveryCost) / float64(point.Revenue)
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения статистики продаж: %w", classifyError(err))
+	}
+	return points, nil
+}
+
+// возвращает бренды с наибольшей выручкой по товарам, топ строится в каждом периоде и измерении
+func (r *OrderRepositoryImpl) GetTopBrands(ctx context.Context, filter AnalyticsFilter, limit int) ([]TopEntry, error) {
+	return r.queryTop(ctx, filter, limit, "i.brand AS brand, 0 AS nm_id, '' AS name", "i.brand")
+}
+
+// возвращает товары (nm_id) с наибольшей выручкой, топ строится в каждом периоде и измерении
+func (r *OrderRepositoryImpl) GetTopProducts(ctx context.Context, filter AnalyticsFilter, limit int) ([]TopEntry, error) {
+	return r.queryTop(ctx, filter, limit, "max(i.brand) AS brand, i.nm_id AS nm_id, max(i.name) AS name", "i.nm_id")
+}
+
+// без interval весь период считается одной группой, без group_by - без разбивки по измерению
+func (r *OrderRepositoryImpl) queryTop(ctx context.Context, filter AnalyticsFilter, limit int, columns, groupBy string) ([]TopEntry, error) {
+	dimension, err := analyticsDimension(filter.Dimension)
+	if err != nil {
+		return nil, err
+	}
+	period := "NULL::timestamp"
+	var args []interface{}
+	if filter.Interval != "" {
+		if err := validateInterval(filter.Interval); err != nil {
+			return nil, err
+		}
+		args = append(args, filter.Interval)
+		period = "date_trunc($1, o.date_created)"
+	}
+
+	where, args := periodConditions(filter, args)
+	args = append(args, limit)
+	query := fmt.Sprintf(`
+		WITH totals AS (
+			SELECT %s AS period, %s AS dimension, %s,
+			       count(*) AS items, count(DISTINCT i.order_uid) AS orders,
+			       COALESCE(sum(i.total_price), 0) AS revenue
+			FROM items i
+			JOIN orders o ON o.order_uid = i.order_uid
+			LEFT JOIN delivery d ON o.order_uid = d.order_uid
+			LEFT JOIN payment p ON o.order_uid = p.order_uid
+			%s
+			GROUP BY 1, 2, %s
+		), ranked AS (
+			SELECT *, row_number() OVER (PARTITION BY period, dimension ORDER BY revenue DESC, items DESC) AS place
+			FROM totals
+		)
+		SELECT period, dimension, brand, nm_id, name, items, orders, revenue
+		FROM ranked
+		WHERE place <= $%d
+		ORDER BY period, dimension, place`, period, dimension, columns, where, groupBy, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса топа товаров: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	entries := []TopEntry{}
+	for rows.Next() {
+		var entry TopEntry
+		var periodStart sql.NullTime
+		var nmID *int
+		if err := rows.Scan(&periodStart, &entry.Dimension, &entry.Brand, &nmID, &entry.Name,
+			&entry.Items, &entry.Orders, &entry.Revenue); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования топа товаров: %w", classifyError(err))
+		}
+		entry.Period = periodStart.Time
+		if nmID != nil {
+			entry.NmID = *nmID
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения топа товаров: %w", classifyError(err))
+	}
+	return entries, nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// API ключ клиента; сам ключ не хранится, только его SHA-256 в hex
+type APIKey struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	KeyHash    string    `json:"-"`
+	Role       string    `json:"role"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+}
+
+type APIKeyRepositoryImpl struct {
+	db *sql.DB
+}
+
+// создает репозиторий API ключей
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &APIKeyRepositoryImpl{db: db}
+}
+
+// сохраняет ключ
+func (r *APIKeyRepositoryImpl) CreateAPIKey(ctx context.Context, key APIKey) (APIKey, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (name, key_hash, role, active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		key.Name, key.KeyHash, key.Role, key.Active,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("ошибка сохранения API ключа: %w", classifyError(err))
+	}
+	return key, nil
+}
+
+// ищет активный ключ по хэшу
+func (r *APIKeyRepositoryImpl) GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, error) {
+	var key APIKey
+	var lastUsed sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, name, key_hash, role, active, created_at, last_used_at
+		FROM api_keys
+		WHERE key_hash = $1 AND active`,
+		keyHash,
+	).Scan(&key.ID, &key.Name, &key.KeyHash, &key.Role, &key.Active, &key.CreatedAt, &lastUsed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return APIKey{}, fmt.Errorf("ошибка поиска API ключа: %w", classifyError(err))
+	}
+	key.LastUsedAt = lastUsed.Time
+	return key, nil
+}
+
+// отмечает время последнего использования ключа
+func (r *APIKeyRepositoryImpl) TouchAPIKey(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления API ключа: %w", classifyError(err))
+	}
+	return nil
+}
@@ -7,7 +7,7 @@ import (
 	"order-service/internal/config"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // DB обертка с реализацией интерфейса
@@ -78,8 +78,8 @@ func (r *OrderRepositoryImpl) GetDB() *sql.DB {
 func (r *OrderRepositoryImpl) SaveOrder(tx *sql.Tx, order Order) error {
 	query := `INSERT INTO orders (
 		order_uid, track_number, entry, locale, internal_signature, 
-		customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
-	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+		customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE($12, now() AT TIME ZONE 'UTC'))`
 
 	_, err := tx.Exec(query,
 		order.OrderUID,
@@ -93,10 +93,11 @@ func (r *OrderRepositoryImpl) SaveOrder(tx *sql.Tx, order Order) error {
 		order.SmID,
 		order.DateCreated,
 		order.OofShard,
+		sql.NullTime{Time: order.UpdatedAt, Valid: !order.UpdatedAt.IsZero()},
 	)
 
 	if err != nil {
-		return fmt.Errorf("ошибка сохранения заказа: %v", err)
+		return fmt.Errorf("ошибка сохранения заказа: %w", classifyOrderError(err))
 	}
 	return nil
 }
@@ -119,7 +120,7 @@ func (r *OrderRepositoryImpl) SaveDelivery(tx *sql.Tx, order Order) error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("ошибка сохранения доставки: %v", err)
+		return fmt.Errorf("ошибка сохранения доставки: %w", classifyOrderError(err))
 	}
 	return nil
 }
@@ -146,7 +147,7 @@ func (r *OrderRepositoryImpl) SavePayment(tx *sql.Tx, order Order) error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("ошибка сохранения платежа: %v", err)
+		return fmt.Errorf("ошибка сохранения платежа: %w", classifyOrderError(err))
 	}
 	return nil
 }
@@ -175,32 +176,36 @@ func (r *OrderRepositoryImpl) SaveItems(tx *sql.Tx, order Order) error {
 		)
 
 		if err != nil {
-			return fmt.Errorf("ошибка сохранения товара: %v", err)
+			return fmt.Errorf("ошибка сохранения товара: %w", classifyOrderError(err))
 		}
 	}
 	return nil
 }
 
-// получает заказ из БД
-func (r *OrderRepositoryImpl) GetOrder(orderUID string) (Order, error) {
-	var order Order
-
-	query := `
+// общая часть запроса заказа с доставкой и платежом
+const orderSelectQuery = `
         SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, 
-               o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+               o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.updated_at,
                d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
                p.transaction, p.request_id, p.currency, p.provider, p.amount, 
                p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee
         FROM orders o
         LEFT JOIN delivery d ON o.order_uid = d.order_uid
         LEFT JOIN payment p ON o.order_uid = p.order_uid
-        WHERE o.order_uid = $1
-    `
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	err := r.db.QueryRow(query, orderUID).Scan(
+// сканирует строку, полученную запросом orderSelectQuery
+func scanOrder(row rowScanner) (Order, error) {
+	var order Order
+	var updatedAt sql.NullTime
+	err := row.Scan(
 		&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale,
 		&order.InternalSignature, &order.CustomerID, &order.DeliveryService,
-		&order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard,
+		&order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard, &updatedAt,
 		&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip,
 		&order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region,
 		&order.Delivery.Email,
@@ -209,18 +214,67 @@ func (r *OrderRepositoryImpl) GetOrder(orderUID string) (Order, error) {
 		&order.Payment.Bank, &order.Payment.DeliveryCost, &order.Payment.GoodsTotal,
 		&order.Payment.CustomFee,
 	)
+	// lib/pq возвращает TIMESTAMP со смещением +00:00, приводим к UTC как в ProcessOrder
+	order.UpdatedAt = updatedAt.Time.UTC()
+	return order, err
+}
 
+// получает заказ из БД
+func (r *OrderRepositoryImpl) GetOrder(orderUID string) (Order, error) {
+	order, err := scanOrder(r.db.QueryRow(orderSelectQuery+"WHERE o.order_uid = $1", orderUID))
 	if err != nil {
-		return Order{}, err
+		return Order{}, classifyOrderError(err)
 	}
 
 	if err := r.LoadOrderItems(&order); err != nil {
-		return Order{}, fmt.Errorf("ошибка загрузки товаров: %v", err)
+		return Order{}, fmt.Errorf("ошибка загрузки товаров: %w", err)
 	}
 
 	return order, nil
 }
 
+// получает заказы по трек-номеру, кроме перечисленных в excludeUIDs
+func (r *OrderRepositoryImpl) GetOrdersByTrackNumber(trackNumber string, excludeUIDs []string) ([]Order, error) {
+	return r.queryOrders(context.Background(),
+		orderSelectQuery+`WHERE o.track_number = $1 AND o.order_uid <> ALL($2)
+		ORDER BY o.date_created DESC, o.order_uid`,
+		trackNumber, pq.Array(excludeUIDs))
+}
+
+// получает последние заказы покупателя, кроме перечисленных в excludeUIDs
+func (r *OrderRepositoryImpl) GetOrdersByCustomerID(customerID string, excludeUIDs []string, limit int) ([]Order, error) {
+	return r.queryOrders(context.Background(),
+		orderSelectQuery+`WHERE o.customer_id = $1 AND o.order_uid <> ALL($2)
+		ORDER BY o.date_created DESC, o.order_uid LIMIT $3`,
+		customerID, pq.Array(excludeUIDs), limit)
+}
+
+// выполняет запрос на основе orderSelectQuery и догружает товары пачкой
+func (r *OrderRepositoryImpl) queryOrders(ctx context.Context, query string, args ...interface{}) ([]Order, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса заказов: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	orders := []Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования заказа: %w", classifyError(err))
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения заказов: %w", classifyError(err))
+	}
+
+	if err := r.LoadItemsForOrders(ctx, orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
 // загружает товары для заказа
 func (r *OrderRepositoryImpl) LoadOrderItems(order *Order) error {
 	query := `
@@ -232,7 +286,7 @@ func (r *OrderRepositoryImpl) LoadOrderItems(order *Order) error {
 
 	rows, err := r.db.Query(query, order.OrderUID)
 	if err != nil {
-		return fmt.Errorf("ошибка запроса товаров: %v", err)
+		return fmt.Errorf("ошибка запроса товаров: %w", classifyError(err))
 	}
 	defer rows.Close()
 
@@ -244,7 +298,7 @@ func (r *OrderRepositoryImpl) LoadOrderItems(order *Order) error {
 			&item.Sale, &item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status,
 		)
 		if err != nil {
-			return fmt.Errorf("ошибка сканирования товара: %v", err)
+			return fmt.Errorf("ошибка сканирования товара: %w", classifyError(err))
 		}
 		items = append(items, item)
 	}
@@ -253,6 +307,127 @@ func (r *OrderRepositoryImpl) LoadOrderItems(order *Order) error {
 	return nil
 }
 
+// загружает товары сразу для пачки заказов одним запросом
+func (r *OrderRepositoryImpl) LoadItemsForOrders(ctx context.Context, orders []Order) error {
+	return loadItemsForOrders(ctx, r.db, orders)
+}
+
+// общий интерфейс *sql.DB и *sql.Tx для чтения
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func loadItemsForOrders(ctx context.Context, q queryer, orders []Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	uids := make([]string, len(orders))
+	index := make(map[string]int, len(orders))
+	for i, order := range orders {
+		uids[i] = order.OrderUID
+		index[order.OrderUID] = i
+	}
+
+	query := `
+		SELECT order_uid, chrt_id, track_number, price, rid, name, 
+			   sale, size, total_price, nm_id, brand, status
+		FROM items 
+		WHERE order_uid = ANY($1)
+		ORDER BY id
+	`
+
+	rows, err := q.QueryContext(ctx, query, pq.Array(uids))
+	if err != nil {
+		return fmt.Errorf("ошибка запроса товаров: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderUID string
+		var item Item
+		err := rows.Scan(
+			&orderUID, &item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name,
+			&item.Sale, &item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status,
+		)
+		if err != nil {
+			return fmt.Errorf("ошибка сканирования товара: %w", classifyError(err))
+		}
+		if i, ok := index[orderUID]; ok {
+			orders[i].Items = append(orders[i].Items, item)
+		}
+	}
+
+	return rows.Err()
+}
+
+// постранично читает последние заказы вместе с товарами и передает страницы в fn
+func (r *OrderRepositoryImpl) StreamOrders(ctx context.Context, limit, batchSize int, fn func(orders []Order) error) error {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	var lastDate time.Time
+	var lastUID string
+	loaded := 0
+
+	for loaded < limit {
+		pageSize := min(batchSize, limit-loaded)
+
+		var rows *sql.Rows
+		var err error
+		if loaded == 0 {
+			rows, err = r.db.QueryContext(ctx,
+				orderSelectQuery+"ORDER BY o.date_created DESC, o.order_uid DESC LIMIT $1",
+				pageSize)
+		} else {
+			rows, err = r.db.QueryContext(ctx,
+				orderSelectQuery+`WHERE (o.date_created, o.order_uid) < ($1, $2)
+				ORDER BY o.date_created DESC, o.order_uid DESC LIMIT $3`,
+				lastDate, lastUID, pageSize)
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка запроса заказов: %w", classifyError(err))
+		}
+
+		orders := make([]Order, 0, pageSize)
+		for rows.Next() {
+			order, err := scanOrder(rows)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("ошибка сканирования заказа: %w", classifyError(err))
+			}
+			orders = append(orders, order)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("ошибка чтения заказов: %w", classifyError(err))
+		}
+
+		if len(orders) == 0 {
+			return nil
+		}
+
+		if err := r.LoadItemsForOrders(ctx, orders); err != nil {
+			return err
+		}
+
+		if err := fn(orders); err != nil {
+			return err
+		}
+
+		loaded += len(orders)
+		last := orders[len(orders)-1]
+		lastDate, lastUID = last.DateCreated, last.OrderUID
+
+		if len(orders) < pageSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
 func (r *OrderRepositoryImpl) CheckConnection() error {
-	return r.db.Ping()
+	return classifyError(r.db.Ping())
 }
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"order-service/internal/config"
 	"time"
@@ -27,6 +28,35 @@ type OrderRepository interface {
 	SavePayment(tx *sql.Tx, order Order) error
 	SaveItems(tx *sql.Tx, order Order) error
 	GetOrder(orderUID string) (Order, error)
+	GetOrdersByTrackNumber(trackNumber string, excludeUIDs []string) ([]Order, error)
+	GetOrdersByCustomerID(customerID string, excludeUIDs []string, limit int) ([]Order, error)
 	LoadOrderItems(order *Order) error
+	LoadItemsForOrders(ctx context.Context, orders []Order) error
+	StreamOrders(ctx context.Context, limit, batchSize int, fn func(orders []Order) error) error
+	SearchOrders(ctx context.Context, filter OrderSearchFilter) (OrderSearchResult, error)
+	ExportOrders(ctx context.Context, filter OrderSearchFilter, fn func(order Order) error) error
+	SearchOrdersText(ctx context.Context, text string, limit, offset int) ([]OrderTextMatch, error)
+	GetSalesStats(ctx context.Context, filter AnalyticsFilter) ([]SalesPoint, error)
+	GetTopBrands(ctx context.Context, filter AnalyticsFilter, limit int) ([]TopEntry, error)
+	GetTopProducts(ctx context.Context, filter AnalyticsFilter, limit int) ([]TopEntry, error)
 	CheckConnection() error
 }
+
+// интерфейс для подписок на вебхуки и журнала их доставки
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub WebhookSubscription) (WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	EnqueueOrderDeliveries(ctx context.Context, tx *sql.Tx, order Order, event string, payload []byte) (int, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery WebhookDelivery, retryIn time.Duration) error
+	ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, id int64) error
+}
+
+// интерфейс для API ключей, по ключу ищется только его хэш
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key APIKey) (APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, error)
+	TouchAPIKey(ctx context.Context, id int64) error
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// записи нет в БД; ошибки отдельных ресурсов ниже оборачивают ее
+	ErrNotFound = errors.New("запись не найдена")
+	// запись с таким ключом уже есть (нарушено ограничение уникальности)
+	ErrConflict = errors.New("запись уже существует")
+	// заказа нет в БД
+	ErrOrderNotFound = resourceError("заказ не найден", ErrNotFound)
+	// заказ с таким order_uid уже сохранен
+	ErrDuplicateOrder = resourceError("заказ уже существует", ErrConflict)
+	// неверные параметры запроса к репозиторию
+	ErrInvalidArgument = errors.New("неверный параметр запроса")
+	// БД недоступна: нет соединения, таймаут или сервер не принимает запросы
+	ErrUnavailable = errors.New("база данных недоступна")
+	// подписки или доставки вебхука нет в БД
+	ErrWebhookNotFound = resourceError("вебхук не найден", ErrNotFound)
+	// API ключа нет в БД или он отключен
+	ErrAPIKeyNotFound = resourceError("API ключ не найден", ErrNotFound)
+)
+
+// ошибка конкретного ресурса: errors.Is находит и ее, и общую ошибку kind
+type resourceErr struct {
+	message string
+	kind    error
+}
+
+func resourceError(message string, kind error) error {
+	return &resourceErr{message: message, kind: kind}
+}
+
+func (e *resourceErr) Error() string { return e.message }
+
+func (e *resourceErr) Unwrap() error { return e.kind }
+
+// коды unique_violation и классы ошибок Postgres, означающие недоступность сервера
+const pqUniqueViolation = "23505"
+
+var pqUnavailableClasses = []string{
+	"08",  // connection_exception
+	"53",  // insufficient_resources
+	"57P", // admin_shutdown, crash_shutdown, cannot_connect_now
+}
+
+// приводит ошибку драйвера к ошибкам пакета, сохраняя исходную в цепочке;
+// отсутствие записи и нарушение уникальности дают общие ErrNotFound и ErrConflict
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code == pqUniqueViolation {
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		}
+		for _, class := range pqUnavailableClasses {
+			if strings.HasPrefix(string(pqErr.Code), class) {
+				return fmt.Errorf("%w: %w", ErrUnavailable, err)
+			}
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	return err
+}
+
+// как classifyError, но отсутствие записи и конфликт относятся к заказу
+func classifyOrderError(err error) error {
+	err = classifyError(err)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return fmt.Errorf("%w: %w", ErrOrderNotFound, err)
+	case errors.Is(err, ErrConflict):
+		return fmt.Errorf("%w: %w", ErrDuplicateOrder, err)
+	}
+	return err
+}
@@ -19,6 +19,8 @@ type Order struct {
 	SmID              int       `json:"sm_id" validate:"required,min=0"`
 	DateCreated       time.Time `json:"date_created" validate:"required"`
 	OofShard          string    `json:"oof_shard" validate:"required,alphanumdash"`
+	// время последнего изменения в БД, в UTC; основа Last-Modified в HTTP API
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
 }
 
 type Delivery struct {
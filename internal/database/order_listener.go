@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"order-service/internal/config"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// канал уведомлений, в который пишет триггер notify_order_change
+const OrderChangesChannel = "order_changes"
+
+// операции над заказом из уведомления
+const (
+	OrderChangeInsert = "INSERT"
+	OrderChangeUpdate = "UPDATE"
+	OrderChangeDelete = "DELETE"
+	// соединение переподключилось, уведомления за время разрыва потеряны
+	OrderChangeResync = "RESYNC"
+)
+
+// уведомление об изменении заказа
+type OrderChange struct {
+	Op       string `json:"op"`
+	OrderUID string `json:"order_uid"`
+}
+
+// слушает изменения заказов до отмены контекста, при разрыве соединения переподключается
+func ListenOrderChanges(ctx context.Context, cfg config.DatabaseConfig, handle func(change OrderChange)) error {
+	listener := pq.NewListener(buildConnectionString(cfg), time.Second, time.Minute,
+		func(event pq.ListenerEventType, err error) {
+			switch event {
+			case pq.ListenerEventDisconnected:
+				log.Printf("Соединение LISTEN потеряно: %v", err)
+			case pq.ListenerEventReconnected:
+				log.Println("Соединение LISTEN восстановлено")
+			case pq.ListenerEventConnectionAttemptFailed:
+				log.Printf("Ошибка подключения LISTEN: %v", err)
+			}
+		})
+	defer listener.Close()
+
+	if err := listener.Listen(OrderChangesChannel); err != nil {
+		return err
+	}
+	log.Printf("Подписались на изменения заказов: %s", OrderChangesChannel)
+
+	ticker := time.NewTicker(90 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Слушатель изменений заказов остановлен")
+			return nil
+
+		case notification := <-listener.Notify:
+			// nil приходит после переподключения
+			if notification == nil {
+				handle(OrderChange{Op: OrderChangeResync})
+				continue
+			}
+
+			var change OrderChange
+			if err := json.Unmarshal([]byte(notification.Extra), &change); err != nil {
+				log.Printf("Некорректное уведомление об изменении заказа: %v", err)
+				continue
+			}
+			handle(change)
+
+		case <-ticker.C:
+			// проверяем соединение, если уведомлений давно не было
+			go func() {
+				if err := listener.Ping(); err != nil {
+					log.Printf("Ошибка проверки соединения LISTEN: %v", err)
+				}
+			}()
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// поля сортировки результатов поиска
+const (
+	SortByDateCreated = "date_created"
+	SortByAmount      = "amount"
+)
+
+// ErrInvalidCursor возвращается, если курсор поврежден или получен при другой сортировке
+var ErrInvalidCursor = errors.New("неверный курсор")
+
+// выражения сортировки, к ним всегда добавляется o.order_uid для однозначного порядка
+var orderSortColumns = map[string]string{
+	SortByDateCreated: "o.date_created",
+	SortByAmount:      "COALESCE(p.amount, 0)",
+}
+
+// разбирает границу периода фильтров: время RFC3339 или дату YYYY-MM-DD;
+// для верхней границы дата включается целиком, пустая строка - без ограничения
+func ParseFilterTime(value string, upperBound bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, errors.New("ожидается дата YYYY-MM-DD или время RFC3339")
+	}
+	if upperBound {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// фильтры поиска заказов; пустые поля не ограничивают выборку
+type OrderSearchFilter struct {
+	CustomerID      string
+	TrackNumber     string
+	DeliveryService string
+	PaymentProvider string
+	Currency        string
+	Brand           string
+	NmID            int
+	CreatedFrom     time.Time
+	CreatedTo       time.Time
+	MinAmount       int
+	MaxAmount       int
+
+	SortBy string
+	Desc   bool
+	Limit  int
+	// курсор из NextCursor предыдущей страницы
+	Cursor string
+}
+
+// страница результатов поиска
+type OrderSearchResult struct {
+	Orders []Order
+	// пустой, если страница последняя
+	NextCursor string
+}
+
+// позиция последней строки страницы
+type searchCursor struct {
+	SortBy   string    `json:"s"`
+	Desc     bool      `json:"d"`
+	Date     time.Time `json:"t,omitzero"`
+	Amount   int       `json:"a,omitempty"`
+	OrderUID string    `json:"u"`
+}
+
+func encodeCursor(c searchCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (searchCursor, error) {
+	var c searchCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.OrderUID == "" {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// собирает условия WHERE по фильтрам; номера параметров начинаются с $1
+func searchConditions(filter OrderSearchFilter) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.CustomerID != "" {
+		addCondition("o.customer_id = $%d", filter.CustomerID)
+	}
+	if filter.TrackNumber != "" {
+		addCondition("o.track_number = $%d", filter.TrackNumber)
+	}
+	if filter.DeliveryService != "" {
+		addCondition("o.delivery_service = $%d", filter.DeliveryService)
+	}
+	if filter.PaymentProvider != "" {
+		addCondition("p.provider = $%d", filter.PaymentProvider)
+	}
+	if filter.Currency != "" {
+		addCondition("p.currency = $%d", filter.Currency)
+	}
+	if filter.Brand != "" {
+		addCondition("EXISTS (SELECT 1 FROM items i WHERE i.order_uid = o.order_uid AND i.brand = $%d)", filter.Brand)
+	}
+	if filter.NmID != 0 {
+		addCondition("EXISTS (SELECT 1 FROM items i WHERE i.order_uid = o.order_uid AND i.nm_id = $%d)", filter.NmID)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		addCondition("o.date_created >= $%d", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		addCondition("o.date_created < $%d", filter.CreatedTo)
+	}
+	if filter.MinAmount > 0 {
+		addCondition("p.amount >= $%d", filter.MinAmount)
+	}
+	if filter.MaxAmount > 0 {
+		addCondition("p.amount <= $%d", filter.MaxAmount)
+	}
+
+	return conditions, args
+}
+
+// ищет заказы по фильтрам с сортировкой и постраничным выводом по курсору
+func (r *OrderRepositoryImpl) SearchOrders(ctx context.Context, filter OrderSearchFilter) (OrderSearchResult, error) {
+	if filter.SortBy == "" {
+		filter.SortBy = SortByDateCreated
+	}
+	sortColumn, ok := orderSortColumns[filter.SortBy]
+	if !ok {
+		return OrderSearchResult{}, fmt.Errorf("%w: неизвестное поле сортировки %s", ErrInvalidArgument, filter.SortBy)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+
+	conditions, args := searchConditions(filter)
+
+	direction, comparison := "ASC", ">"
+	if filter.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeCursor(filter.Cursor)
+		if err != nil || cursor.SortBy != filter.SortBy || cursor.Desc != filter.Desc {
+			return OrderSearchResult{}, ErrInvalidCursor
+		}
+		var value interface{} = cursor.Date
+		if filter.SortBy == SortByAmount {
+			value = cursor.Amount
+		}
+		args = append(args, value, cursor.OrderUID)
+		conditions = append(conditions, fmt.Sprintf("(%s, o.order_uid) %s ($%d, $%d)",
+			sortColumn, comparison, len(args)-1, len(args)))
+	}
+
+	query := orderSelectQuery
+	if len(conditions) > 0 {
+		query += "WHERE " + strings.Join(conditions, " AND ") + "\n"
+	}
+	// берем на одну строку больше, чтобы узнать, есть ли следующая страница
+	args = append(args, filter.Limit+1)
+	query += fmt.Sprintf("ORDER BY %s %s, o.order_uid %s LIMIT $%d", sortColumn, direction, direction, len(args))
+
+	orders, err := r.queryOrders(ctx, query, args...)
+	if err != nil {
+		return OrderSearchResult{}, err
+	}
+
+	result := OrderSearchResult{Orders: orders}
+	if len(orders) > filter.Limit {
+		result.Orders = orders[:filter.Limit]
+		last := result.Orders[len(result.Orders)-1]
+		result.NextCursor = encodeCursor(searchCursor{
+			SortBy:   filter.SortBy,
+			Desc:     filter.Desc,
+			Date:     last.DateCreated,
+			Amount:   last.Payment.Amount,
+			OrderUID: last.OrderUID,
+		})
+	}
+	return result, nil
+}
+
+// заказ, найденный полнотекстовым поиском по товарам
+type OrderTextMatch struct {
+	Order Order   `json:"order"`
+	Rank  float64 `json:"rank"`
+	// фрагменты названий совпавших товаров, экранированные для HTML;
+	// найденные слова обернуты в <mark>
+	Snippets []string `json:"snippets"`
+}
+
+// ts_headline отмечает найденные слова управляющими символами, а не тегами:
+// текст товара экранируется уже после подсветки, см. highlightSnippet
+const (
+	snippetStartSel = '\x02'
+	snippetStopSel  = '\x03'
+)
+
+// ранжирует заказы по совпадению запроса с названиями и брендами товаров;
+// запрос разбирается в русской и английской конфигурациях, подсветка идет
+// в обеих конфигурациях по очереди, как и построение search_vector
+const orderTextSearchQuery = `
+	WITH q AS (
+		SELECT websearch_to_tsquery('russian', $1) AS ru,
+		       websearch_to_tsquery('english', $1) AS en,
+		       'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', HighlightAll=true' AS options
+	)
+	SELECT i.order_uid,
+	       max(ts_rank(i.search_vector, q.ru || q.en)) AS rank,
+	       array_agg(ts_headline('english',
+	                 ts_headline('russian', translate(i.brand || ' ' || i.name, chr(2) || chr(3), ''), q.ru, q.options),
+	                 q.en, q.options) ORDER BY i.id) AS snippets
+	FROM items i, q
+	WHERE i.search_vector @@ (q.ru || q.en)
+	GROUP BY i.order_uid
+	ORDER BY rank DESC, i.order_uid
+	LIMIT $2 OFFSET $3
+`
+
+// экранирует фрагмент для HTML и заменяет отметки ts_headline на <mark>;
+// слово, найденное в обеих конфигурациях, отмечено дважды и оборачивается один раз
+func highlightSnippet(snippet string) string {
+	var b strings.Builder
+	depth := 0
+	for {
+		i := strings.IndexAny(snippet, string([]rune{snippetStartSel, snippetStopSel}))
+		if i < 0 {
+			break
+		}
+		b.WriteString(html.EscapeString(snippet[:i]))
+		switch rune(snippet[i]) {
+		case snippetStartSel:
+			if depth == 0 {
+				b.WriteString("<mark>")
+			}
+			depth++
+		case snippetStopSel:
+			if depth == 1 {
+				b.WriteString("</mark>")
+			}
+			if depth > 0 {
+				depth--
+			}
+		}
+		snippet = snippet[i+1:]
+	}
+	b.WriteString(html.EscapeString(snippet))
+	if depth > 0 {
+		b.WriteString("</mark>")
+	}
+	return b.String()
+}
+
+// ищет заказы по названию и бренду товаров, сначала самые релевантные
+func (r *OrderRepositoryImpl) SearchOrdersText(ctx context.Context, text string, limit, offset int) ([]OrderTextMatch, error) {
+	rows, err := r.db.QueryContext(ctx, orderTextSearchQuery, text, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка полнотекстового поиска: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	matches := []OrderTextMatch{}
+	var uids []string
+	for rows.Next() {
+		var match OrderTextMatch
+		var snippets pq.StringArray
+		if err := rows.Scan(&match.Order.OrderUID, &match.Rank, &snippets); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования результата поиска: %w", classifyError(err))
+		}
+		match.Snippets = make([]string, len(snippets))
+		for i, snippet := range snippets {
+			match.Snippets[i] = highlightSnippet(snippet)
+		}
+		matches = append(matches, match)
+		uids = append(uids, match.Order.OrderUID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения результатов поиска: %w", classifyError(err))
+	}
+	if len(matches) == 0 {
+		return matches, nil
+	}
+
+	// сами заказы загружаем общим запросом и раскладываем в порядке релевантности
+	orders, err := r.queryOrders(ctx, orderSelectQuery+"WHERE o.order_uid = ANY($1)", pq.Array(uids))
+	if err != nil {
+		return nil, err
+	}
+	byUID := make(map[string]Order, len(orders))
+	for _, order := range orders {
+		byUID[order.OrderUID] = order
+	}
+
+	found := matches[:0]
+	for _, match := range matches {
+		// заказ могли удалить между запросами
+		if order, ok := byUID[match.Order.OrderUID]; ok {
+			match.Order = order
+			found = append(found, match)
+		}
+	}
+	return found, nil
+}
+
+// размер пачки, читаемой из серверного курсора выгрузки
+const exportFetchSize = 500
+
+// выгружает все заказы по фильтрам и передает их в fn по одному;
+// строки читаются серверным курсором пачками, поэтому память не растет с объемом выгрузки.
+// filter.Limit ограничивает количество заказов, 0 - без ограничения; курсор поиска не используется
+func (r *OrderRepositoryImpl) ExportOrders(ctx context.Context, filter OrderSearchFilter, fn func(order Order) error) error {
+	if filter.SortBy == "" {
+		filter.SortBy = SortByDateCreated
+	}
+	sortColumn, ok := orderSortColumns[filter.SortBy]
+	if !ok {
+		return fmt.Errorf("%w: неизвестное поле сортировки %s", ErrInvalidArgument, filter.SortBy)
+	}
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
+
+	conditions, args := searchConditions(filter)
+	query := orderSelectQuery
+	if len(conditions) > 0 {
+		query += "WHERE " + strings.Join(conditions, " AND ") + "\n"
+	}
+	query += fmt.Sprintf("ORDER BY %s %s, o.order_uid %s", sortColumn, direction, direction)
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	// курсор живет только внутри транзакции; товары читаются в том же снимке данных
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции выгрузки: %w", classifyError(err))
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DECLARE export_cursor NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return fmt.Errorf("ошибка открытия курсора выгрузки: %w", classifyError(err))
+	}
+
+	fetchQuery := fmt.Sprintf("FETCH %d FROM export_cursor", exportFetchSize)
+	for {
+		rows, err := tx.QueryContext(ctx, fetchQuery)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения курсора выгрузки: %w", classifyError(err))
+		}
+
+		orders := make([]Order, 0, exportFetchSize)
+		for rows.Next() {
+			order, err := scanOrder(rows)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("ошибка сканирования заказа: %w", classifyError(err))
+			}
+			orders = append(orders, order)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("ошибка чтения заказов: %w", classifyError(err))
+		}
+
+		if len(orders) == 0 {
+			return nil
+		}
+		if err := loadItemsForOrders(ctx, tx, orders); err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+
+		if len(orders) < exportFetchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// выполняет fn внутри точки сохранения: при ошибке откатываются только изменения fn,
+// и транзакция остается пригодной для следующих операций
+func WithSavepoint(ctx context.Context, tx *sql.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT order_savepoint"); err != nil {
+		return fmt.Errorf("ошибка создания точки сохранения: %w", classifyError(err))
+	}
+
+	if err := fn(); err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT order_savepoint"); rollbackErr != nil {
+			return fmt.Errorf("ошибка отката к точке сохранения: %w", classifyError(rollbackErr))
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT order_savepoint"); err != nil {
+		return fmt.Errorf("ошибка освобождения точки сохранения: %w", classifyError(err))
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// статусы доставки вебхука
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// подписка партнера на уведомления; пустые фильтры пропускают любые заказы
+type WebhookSubscription struct {
+	ID              int64     `json:"id"`
+	URL             string    `json:"url"`
+	Secret          string    `json:"secret,omitempty"`
+	DeliveryService string    `json:"delivery_service,omitempty"`
+	Entry           string    `json:"entry,omitempty"`
+	Active          bool      `json:"active"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// попытка доставки события одной подписке
+type WebhookDelivery struct {
+	ID             int64     `json:"id"`
+	SubscriptionID int64     `json:"subscription_id"`
+	OrderUID       string    `json:"order_uid"`
+	Event          string    `json:"event"`
+	Payload        []byte    `json:"-"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	LastStatusCode int       `json:"last_status_code,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	DeliveredAt    time.Time `json:"delivered_at,omitzero"`
+	// заполняются при захвате доставки на отправку
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+type WebhookRepositoryImpl struct {
+	db *sql.DB
+}
+
+// создает репозиторий вебхуков
+func NewWebhookRepository(db *sql.DB) WebhookRepository {
+	return &WebhookRepositoryImpl{db: db}
+}
+
+// сохраняет подписку
+func (r *WebhookRepositoryImpl) CreateSubscription(ctx context.Context, sub WebhookSubscription) (WebhookSubscription, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO webhook_subscriptions (url, secret, delivery_service, entry, active)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5)
+		RETURNING id, created_at`,
+		sub.URL, sub.Secret, sub.DeliveryService, sub.Entry, sub.Active,
+	).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return WebhookSubscription{}, fmt.Errorf("ошибка сохранения подписки: %w", classifyError(err))
+	}
+	return sub, nil
+}
+
+// возвращает все подписки без секретов
+func (r *WebhookRepositoryImpl) ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, url, COALESCE(delivery_service, ''), COALESCE(entry, ''), active, created_at
+		FROM webhook_subscriptions
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса подписок: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	subs := []WebhookSubscription{}
+	for rows.Next() {
+		var sub WebhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.DeliveryService, &sub.Entry, &sub.Active, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования подписки: %w", classifyError(err))
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения подписок: %w", classifyError(err))
+	}
+	return subs, nil
+}
+
+// удаляет подписку вместе с журналом доставки
+func (r *WebhookRepositoryImpl) DeleteSubscription(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления подписки: %w", classifyError(err))
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("подписка %d: %w", id, ErrWebhookNotFound)
+	}
+	return nil
+}
+
+// ставит доставку события всем активным подпискам, фильтры которых пропускают заказ;
+// выполняется в транзакции сохранения заказа, поэтому заказ без уведомлений не сохранится
+func (r *WebhookRepositoryImpl) EnqueueOrderDeliveries(ctx context.Context, tx *sql.Tx, order Order, event string, payload []byte) (int, error) {
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, order_uid, event, payload)
+		SELECT id, $3::varchar, $4::varchar, $5::text
+		FROM webhook_subscriptions
+		WHERE active
+		  AND (delivery_service IS NULL OR delivery_service = $1)
+		  AND (entry IS NULL OR entry = $2)`,
+		order.DeliveryService, order.Entry, order.OrderUID, event, string(payload))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка постановки доставок в очередь: %w", classifyError(err))
+	}
+	queued, _ := result.RowsAffected()
+	return int(queued), nil
+}
+
+// захватывает доставки активных подписок, время которых пришло, и откладывает их на lease,
+// чтобы другие реплики не отправили их одновременно; доставки отключенных подписок
+// остаются в очереди до повторного включения
+func (r *WebhookRepositoryImpl) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= now() AND s.active
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = now() + $2 * interval '1 millisecond'
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.order_uid, d.event, d.payload, d.attempts, s.url, s.secret`,
+		limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка захвата доставок: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var delivery WebhookDelivery
+		var payload string
+		if err := rows.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.OrderUID, &delivery.Event,
+			&payload, &delivery.Attempts, &delivery.URL, &delivery.Secret); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования доставки: %w", classifyError(err))
+		}
+		delivery.Payload = []byte(payload)
+		delivery.Status = WebhookPending
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения доставок: %w", classifyError(err))
+	}
+	return deliveries, nil
+}
+
+// сохраняет результат попытки доставки; время следующей попытки и доставки
+// считается по часам БД, чтобы не зависеть от часового пояса сервиса
+func (r *WebhookRepositoryImpl) UpdateDelivery(ctx context.Context, delivery WebhookDelivery, retryIn time.Duration) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, last_status_code = $4, last_error = $5,
+		    next_attempt_at = now() + $6 * interval '1 millisecond',
+		    delivered_at = CASE WHEN $7 THEN now() END
+		WHERE id = $1`,
+		delivery.ID, delivery.Status, delivery.Attempts, delivery.LastStatusCode, delivery.LastError,
+		retryIn.Milliseconds(), delivery.Status == WebhookDelivered)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления доставки: %w", classifyError(err))
+	}
+	return nil
+}
+
+// возвращает последние доставки подписки
+func (r *WebhookRepositoryImpl) ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, subscription_id, order_uid, event, status, attempts, last_status_code,
+		       last_error, next_attempt_at, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY id DESC
+		LIMIT $2`, subscriptionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса доставок: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var delivery WebhookDelivery
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.OrderUID, &delivery.Event,
+			&delivery.Status, &delivery.Attempts, &delivery.LastStatusCode, &delivery.LastError,
+			&delivery.NextAttemptAt, &delivery.CreatedAt, &deliveredAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования доставки: %w", classifyError(err))
+		}
+		delivery.DeliveredAt = deliveredAt.Time
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения доставок: %w", classifyError(err))
+	}
+	return deliveries, nil
+}
+
+// возвращает доставку в очередь с обнуленным счетчиком попыток
+func (r *WebhookRepositoryImpl) RetryDelivery(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now(), delivered_at = NULL
+		WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка повторной постановки доставки: %w", classifyError(err))
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("доставка %d: %w", id, ErrWebhookNotFound)
+	}
+	return nil
+}
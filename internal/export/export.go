@@ -0,0 +1,153 @@
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"order-service/internal/database"
+	"strconv"
+	"time"
+)
+
+// форматы выгрузки
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// построчная запись заказов в выбранном формате
+type OrderWriter interface {
+	WriteOrder(order database.Order) error
+	// дописывает буферы и закрывает сжатие, сам w не закрывается
+	Close() error
+}
+
+// создает writer для формата; при compress вывод сжимается gzip
+func NewOrderWriter(w io.Writer, format string, compress bool) (OrderWriter, error) {
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+	buffered := bufio.NewWriter(w)
+
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonWriter{buffered: buffered, gz: gz, encoder: json.NewEncoder(buffered)}, nil
+	case FormatCSV:
+		writer := &csvWriter{buffered: buffered, gz: gz, csv: csv.NewWriter(buffered)}
+		if err := writer.csv.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return writer, nil
+	default:
+		return nil, fmt.Errorf("неизвестный формат выгрузки: %s", format)
+	}
+}
+
+// расширение файла выгрузки с учетом сжатия
+func FileExtension(format string, compress bool) string {
+	if compress {
+		return format + ".gz"
+	}
+	return format
+}
+
+// Content-Type выгрузки с учетом сжатия
+func ContentType(format string, compress bool) string {
+	switch {
+	case compress:
+		return "application/gzip"
+	case format == FormatCSV:
+		return "text/csv; charset=utf-8"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+func closeWriters(buffered *bufio.Writer, gz *gzip.Writer) error {
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	if gz != nil {
+		return gz.Close()
+	}
+	return nil
+}
+
+// одна строка JSON на заказ со всеми вложенными данными
+type ndjsonWriter struct {
+	buffered *bufio.Writer
+	gz       *gzip.Writer
+	encoder  *json.Encoder
+}
+
+func (w *ndjsonWriter) WriteOrder(order database.Order) error {
+	return w.encoder.Encode(order)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return closeWriters(w.buffered, w.gz)
+}
+
+// одна строка на товар, поля заказа, доставки и платежа повторяются;
+// заказ без товаров дает одну строку с пустыми колонками товара
+type csvWriter struct {
+	buffered *bufio.Writer
+	gz       *gzip.Writer
+	csv      *csv.Writer
+}
+
+var csvHeader = []string{
+	"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id",
+	"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city", "delivery_address",
+	"delivery_region", "delivery_email",
+	"payment_transaction", "payment_request_id", "payment_currency", "payment_provider",
+	"payment_amount", "payment_dt", "payment_bank", "payment_delivery_cost",
+	"payment_goods_total", "payment_custom_fee",
+	"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name", "item_sale",
+	"item_size", "item_total_price", "item_nm_id", "item_brand", "item_status",
+}
+
+func (w *csvWriter) WriteOrder(order database.Order) error {
+	orderColumns := []string{
+		order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+		order.CustomerID, order.DeliveryService, order.Shardkey, strconv.Itoa(order.SmID),
+		order.DateCreated.Format(time.RFC3339), order.OofShard,
+		order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City,
+		order.Delivery.Address, order.Delivery.Region, order.Delivery.Email,
+		order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency,
+		order.Payment.Provider, strconv.Itoa(order.Payment.Amount),
+		strconv.FormatInt(order.Payment.PaymentDt, 10), order.Payment.Bank,
+		strconv.Itoa(order.Payment.DeliveryCost), strconv.Itoa(order.Payment.GoodsTotal),
+		strconv.Itoa(order.Payment.CustomFee),
+	}
+
+	if len(order.Items) == 0 {
+		return w.csv.Write(append(orderColumns, make([]string, len(csvHeader)-len(orderColumns))...))
+	}
+
+	for _, item := range order.Items {
+		row := append(orderColumns[:len(orderColumns):len(orderColumns)],
+			strconv.Itoa(item.ChrtID), item.TrackNumber, strconv.Itoa(item.Price), item.Rid,
+			item.Name, strconv.Itoa(item.Sale), item.Size, strconv.Itoa(item.TotalPrice),
+			strconv.Itoa(item.NmID), item.Brand, strconv.Itoa(item.Status),
+		)
+		if err := w.csv.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	return closeWriters(w.buffered, w.gz)
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"order-service/internal/database"
+	"testing"
+	"time"
+)
+
+func testOrders() []database.Order {
+	return []database.Order{
+		{
+			OrderUID:    "export1",
+			TrackNumber: "TRACK1",
+			DateCreated: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			Payment:     database.Payment{Amount: 300, Currency: "RUB"},
+			Items: []database.Item{
+				{ChrtID: 1, Name: "Mascaras", Brand: "Vivienne Sabo", TotalPrice: 100},
+				{ChrtID: 2, Name: "Lipstick", Brand: "Vivienne Sabo", TotalPrice: 200},
+			},
+		},
+		{OrderUID: "export2", TrackNumber: "TRACK2"},
+	}
+}
+
+func writeOrders(t *testing.T, format string, compress bool) []byte {
+	var buf bytes.Buffer
+	writer, err := NewOrderWriter(&buf, format, compress)
+	if err != nil {
+		t.Fatalf("Ошибка создания writer: %v", err)
+	}
+	for _, order := range testOrders() {
+		if err := writer.WriteOrder(order); err != nil {
+			t.Fatalf("Ошибка записи заказа: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Ошибка закрытия writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVExport(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(writeOrders(t, FormatCSV, false))).ReadAll()
+	if err != nil {
+		t.Fatalf("Ошибка чтения CSV: %v", err)
+	}
+
+	// заголовок, две строки товаров первого заказа и одна строка заказа без товаров
+	if len(records) != 4 {
+		t.Fatalf("Ожидалось 4 строки, получено %d", len(records))
+	}
+	if records[1][0] != "export1" || records[2][0] != "export1" || records[3][0] != "export2" {
+		t.Errorf("Неверный порядок строк: %v", records)
+	}
+	if records[2][32] != "Lipstick" {
+		t.Errorf("Ожидался товар Lipstick во второй строке, получено %s", records[2][32])
+	}
+	if records[3][28] != "" {
+		t.Errorf("Колонки товара заказа без товаров должны быть пустыми, получено %v", records[3][28:])
+	}
+}
+
+func TestNDJSONExportGzip(t *testing.T) {
+	reader, err := gzip.NewReader(bytes.NewReader(writeOrders(t, FormatNDJSON, true)))
+	if err != nil {
+		t.Fatalf("Ошибка чтения gzip: %v", err)
+	}
+
+	decoder := json.NewDecoder(reader)
+	var orders []database.Order
+	for decoder.More() {
+		var order database.Order
+		if err := decoder.Decode(&order); err != nil {
+			t.Fatalf("Ошибка декодирования строки: %v", err)
+		}
+		orders = append(orders, order)
+	}
+
+	if len(orders) != 2 || len(orders[0].Items) != 2 {
+		t.Errorf("Ожидалось 2 заказа с вложенными товарами, получено %+v", orders)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := NewOrderWriter(&bytes.Buffer{}, "xml", false); err == nil {
+		t.Error("Ожидалась ошибка для неизвестного формата")
+	}
+}
@@ -0,0 +1,12 @@
+package feed
+
+import "order-service/internal/database"
+
+// интерфейс рассылки событий о новых заказах подключенным клиентам
+type Hub interface {
+	// публикует заказ, не блокируясь на медленных подписчиках
+	OrderIngested(order database.Order)
+	Subscribe(filter Filter) *Subscription
+	// отключает всех подписчиков, новые подписки сразу закрыты
+	Close()
+}
@@ -0,0 +1,84 @@
+package feed
+
+import (
+	"order-service/internal/database"
+	"testing"
+	"time"
+)
+
+func TestHubFilter(t *testing.T) {
+	hub := NewHub(4)
+	all := hub.Subscribe(Filter{})
+	meest := hub.Subscribe(Filter{DeliveryService: "meest"})
+	defer all.Close()
+	defer meest.Close()
+
+	hub.OrderIngested(database.Order{OrderUID: "a", DeliveryService: "meest", Payment: database.Payment{Amount: 100}})
+	hub.OrderIngested(database.Order{OrderUID: "b", DeliveryService: "cdek"})
+
+	if len(all.Events()) != 2 {
+		t.Errorf("Ожидалось 2 события без фильтра, получено %d", len(all.Events()))
+	}
+	if len(meest.Events()) != 1 {
+		t.Fatalf("Ожидалось 1 событие с фильтром, получено %d", len(meest.Events()))
+	}
+	event := <-meest.Events()
+	if event.OrderUID != "a" || event.Amount != 100 || event.ID != 1 {
+		t.Errorf("Неверное событие: %+v", event)
+	}
+}
+
+func TestHubDisconnectsSlowSubscriber(t *testing.T) {
+	hub := NewHub(2)
+	slow := hub.Subscribe(Filter{})
+	fast := hub.Subscribe(Filter{})
+	defer fast.Close()
+
+	// публикация не блокируется, даже если подписчик не читает
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			hub.OrderIngested(database.Order{OrderUID: "order"})
+			<-fast.Events()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Публикация заблокирована медленным подписчиком")
+	}
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("Медленный подписчик не отключен")
+	}
+	if !slow.Lagged() || fast.Lagged() {
+		t.Errorf("Неверный признак отставания: slow=%v fast=%v", slow.Lagged(), fast.Lagged())
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub(1)
+	sub := hub.Subscribe(Filter{})
+	hub.Close()
+
+	select {
+	case <-sub.Done():
+	default:
+		t.Error("Подписка не завершена при закрытии хаба")
+	}
+	if sub.Lagged() {
+		t.Error("Закрытие хаба не означает отставание подписчика")
+	}
+
+	// подписка после закрытия сразу завершена, повторное закрытие безопасно
+	late := hub.Subscribe(Filter{})
+	late.Close()
+	select {
+	case <-late.Done():
+	default:
+		t.Error("Подписка после закрытия хаба не завершена")
+	}
+}
@@ -0,0 +1,155 @@
+package feed
+
+import (
+	"order-service/internal/database"
+	"sync"
+	"time"
+)
+
+// краткие данные о заказе для живой ленты
+type Event struct {
+	ID              uint64    `json:"id"`
+	OrderUID        string    `json:"order_uid"`
+	TrackNumber     string    `json:"track_number"`
+	Entry           string    `json:"entry"`
+	DeliveryService string    `json:"delivery_service"`
+	CustomerID      string    `json:"customer_id"`
+	Amount          int       `json:"amount"`
+	Currency        string    `json:"currency"`
+	Items           int       `json:"items"`
+	DateCreated     time.Time `json:"date_created"`
+	IngestedAt      time.Time `json:"ingested_at"`
+}
+
+// фильтр подписки; пустые поля пропускают любые заказы
+type Filter struct {
+	Entry           string
+	DeliveryService string
+}
+
+func (f Filter) match(event Event) bool {
+	return (f.Entry == "" || f.Entry == event.Entry) &&
+		(f.DeliveryService == "" || f.DeliveryService == event.DeliveryService)
+}
+
+// подписка клиента на ленту
+type Subscription struct {
+	filter Filter
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+	// true, если подписчик отключен из-за переполнения буфера
+	lagged bool
+	hub    *hub
+}
+
+// события подписки; канал не закрывается, окончание подписки сигнализирует Done
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// закрывается, когда подписка завершена клиентом, хабом или из-за отставания
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// true, если клиент не успевал читать события и был отключен
+func (s *Subscription) Lagged() bool {
+	s.hub.mutex.RLock()
+	defer s.hub.mutex.RUnlock()
+	return s.lagged
+}
+
+// отписывает клиента
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+type hub struct {
+	bufferSize  int
+	mutex       sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+	lastID      uint64
+}
+
+// создает хаб; bufferSize - сколько событий может ждать отправки одному клиенту
+func NewHub(bufferSize int) Hub {
+	if bufferSize <= 0 {
+		bufferSize = 64
+	}
+	return &hub{bufferSize: bufferSize, subscribers: make(map[*Subscription]struct{})}
+}
+
+func (h *hub) Subscribe(filter Filter) *Subscription {
+	sub := &Subscription{
+		filter: filter,
+		events: make(chan Event, h.bufferSize),
+		done:   make(chan struct{}),
+		hub:    h,
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closed {
+		h.removeLocked(sub)
+		return sub
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// клиенту, чей буфер полон, событие не отправляется, а сам он отключается:
+// пропуски в ленте незаметны, а переподключившийся клиент начнет с актуальных заказов
+func (h *hub) OrderIngested(order database.Order) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.lastID++
+	event := Event{
+		ID:              h.lastID,
+		OrderUID:        order.OrderUID,
+		TrackNumber:     order.TrackNumber,
+		Entry:           order.Entry,
+		DeliveryService: order.DeliveryService,
+		CustomerID:      order.CustomerID,
+		Amount:          order.Payment.Amount,
+		Currency:        order.Payment.Currency,
+		Items:           len(order.Items),
+		DateCreated:     order.DateCreated,
+		IngestedAt:      time.Now(),
+	}
+
+	for sub := range h.subscribers {
+		if !sub.filter.match(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.lagged = true
+			h.removeLocked(sub)
+		}
+	}
+}
+
+func (h *hub) Close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.closed = true
+	for sub := range h.subscribers {
+		h.removeLocked(sub)
+	}
+}
+
+func (h *hub) remove(sub *Subscription) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.removeLocked(sub)
+}
+
+func (h *hub) removeLocked(sub *Subscription) {
+	delete(h.subscribers, sub)
+	sub.once.Do(func() { close(sub.done) })
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"order-service/internal/service"
+	"strconv"
+)
+
+// максимальный размер страницы списка ключей кэша
+const maxCacheKeysPageSize = 1000
+
+// GET /admin/cache/keys?offset=0&limit=100
+func cacheKeysHandler(orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		offset, err := queryInt(r, "offset", 0)
+		if err != nil || offset < 0 {
+			writeBadRequest(w, r, "offset: ожидается неотрицательное число")
+			return
+		}
+		limit, err := queryInt(r, "limit", 100)
+		if err != nil || limit <= 0 || limit > maxCacheKeysPageSize {
+			writeBadRequest(w, r, "limit: ожидается число от 1 до 1000")
+			return
+		}
+
+		entries, total := orderService.ListCacheEntries(offset, limit)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"total":  total,
+			"offset": offset,
+			"limit":  limit,
+			"keys":   entries,
+		})
+	}
+}
+
+// DELETE /admin/cache/keys/{id}
+func cacheEvictHandler(orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderUID := r.PathValue("id")
+
+		if !orderService.EvictCacheEntry(orderUID) {
+			writeAPIError(w, r, http.StatusNotFound, "not_cached", "Заказа нет в кэше",
+				map[string]interface{}{"order_uid": orderUID})
+			return
+		}
+
+		log.Printf("Заказ %s удален из кэша администратором", orderUID)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"evicted":   true,
+			"order_uid": orderUID,
+		})
+	}
+}
+
+// POST /admin/cache/flush
+func cacheFlushHandler(orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flushed := orderService.FlushCache()
+		log.Printf("Кэш очищен администратором, удалено записей: %d", flushed)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"flushed": flushed})
+	}
+}
+
+// POST /admin/cache/warm {"order_ids": ["..."]}
+func cacheWarmHandler(orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			OrderIDs []string `json:"order_ids"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, "invalid_json", "Тело запроса должно быть JSON объектом", nil)
+			return
+		}
+		if len(request.OrderIDs) == 0 || len(request.OrderIDs) > maxCacheKeysPageSize {
+			writeBadRequest(w, r, "order_ids: ожидается от 1 до 1000 ID")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, orderService.WarmCacheOrders(request.OrderIDs))
+	}
+}
+
+func queryInt(r *http.Request, name string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
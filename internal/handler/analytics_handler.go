@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"order-service/internal/database"
+	"order-service/internal/service"
+)
+
+// максимальный размер топа брендов и товаров
+const maxTopSize = 100
+
+// GET /api/v1/analytics/sales?interval=day&group_by=currency&date_from=2024-01-01&date_to=2024-01-31
+func salesAnalyticsHandler(orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseAnalyticsFilter(r)
+		if err != nil {
+			writeError(w, r, err, nil)
+			return
+		}
+		if filter.Interval == "" {
+			filter.Interval = database.IntervalDay
+		}
+
+		points, err := orderService.GetSalesStats(r.Context(), filter)
+		if err != nil {
+			writeError(w, r, err, nil)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"interval": filter.Interval,
+			"group_by": filter.Dimension,
+			"points":   points,
+		})
+	}
+}
+
+// GET /api/v1/analytics/top-brands и /api/v1/analytics/top-products?limit=10&interval=month&group_by=region;
+// limit применяется к каждому периоду и значению измерения
+func topAnalyticsHandler(orderService service.OrderService, products bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseAnalyticsFilter(r)
+		if err != nil {
+			writeError(w, r, err, nil)
+			return
+		}
+		limit, err := queryInt(r, "limit", 10)
+		if err != nil || limit <= 0 || limit > maxTopSize {
+			writeError(w, r, fmt.Errorf("%w: limit должен быть от 1 до %d", database.ErrInvalidArgument, maxTopSize), nil)
+			return
+		}
+
+		var entries []database.TopEntry
+		if products {
+			entries, err = orderService.GetTopProducts(r.Context(), filter, limit)
+		} else {
+			entries, err = orderService.GetTopBrands(r.Context(), filter, limit)
+		}
+		if err != nil {
+			writeError(w, r, err, nil)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"interval": filter.Interval,
+			"group_by": filter.Dimension,
+			"count":    len(entries),
+			"top":      entries,
+		})
+	}
+}
+
+func parseAnalyticsFilter(r *http.Request) (database.AnalyticsFilter, error) {
+	filter := database.AnalyticsFilter{
+		Interval:  r.URL.Query().Get("interval"),
+		Dimension: r.URL.Query().Get("group_by"),
+	}
+
+	var err error
+	if filter.From, err = queryTime(r, "date_from", false); err != nil {
+		return filter, fmt.Errorf("%w: %w", database.ErrInvalidArgument, err)
+	}
+	if filter.To, err = queryTime(r, "date_to", true); err != nil {
+		return filter, fmt.Errorf("%w: %w", database.ErrInvalidArgument, err)
+	}
+	return filter, nil
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"order-service/internal/auth"
+	"slices"
+	"strings"
+)
+
+// пропускает запросы клиентов с ролью не ниже role и кладет клиента в контекст запроса
+func requireRole(authn auth.Authenticator, role auth.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := authn.Authenticate(r.Context(), credentialsFrom(r))
+		switch {
+		case errors.Is(err, auth.ErrNoCredentials):
+			w.Header().Set("WWW-Authenticate", `Bearer realm="order-service"`)
+			writeAPIError(w, r, http.StatusUnauthorized, "unauthorized", "Требуется API ключ или токен", nil)
+			return
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			log.Printf("Отклонены учетные данные %s %s [%s]: %v", r.Method, r.URL.Path, requestID(r), err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="order-service", error="invalid_token"`)
+			writeAPIError(w, r, http.StatusUnauthorized, "invalid_credentials", "Неверный API ключ или токен", nil)
+			return
+		case err != nil:
+			writeError(w, r, err, nil)
+			return
+		}
+
+		if !principal.Role.Allows(role) {
+			writeAPIError(w, r, http.StatusForbidden, "forbidden", "Недостаточно прав", map[string]interface{}{
+				"required_role": role,
+				"role":          principal.Role,
+			})
+			return
+		}
+
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// X-API-Key или Authorization: Bearer
+func credentialsFrom(r *http.Request) auth.Credentials {
+	creds := auth.Credentials{APIKey: r.Header.Get("X-API-Key")}
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		creds.BearerToken = strings.TrimSpace(token)
+	}
+	return creds
+}
+
+// EventSource и WebSocket в браузере не передают заголовки, поэтому для потоковых
+// маршрутов токен можно передать параметром access_token
+func withQueryToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if token := query.Get("access_token"); token != "" {
+			if r.Header.Get("Authorization") == "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+			// токен не должен попасть дальше в журналы вместе с URL
+			query.Del("access_token")
+			r.URL.RawQuery = query.Encode()
+		}
+		next(w, r)
+	}
+}
+
+// разрешенные источники кросс-доменных запросов; "*" разрешает любой
+type corsPolicy struct {
+	origins []string
+}
+
+func (p corsPolicy) allowed(origin string) bool {
+	return origin != "" && (slices.Contains(p.origins, "*") || slices.Contains(p.origins, origin))
+}
+
+// источник WebSocket: без Origin (не браузер), тот же хост или разрешенный CORS
+func (p corsPolicy) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if _, host, ok := strings.Cut(origin, "://"); ok && strings.EqualFold(host, r.Host) {
+		return true
+	}
+	return p.allowed(origin)
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"order-service/internal/service"
+)
+
+// GET /benchmark/{id}?iterations=1000&concurrency=4 - задержки чтения заказа из кэша и из БД
+func benchmarkHandler(orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderUID := r.PathValue("id")
+
+		iterations, err := queryInt(r, "iterations", service.DefaultBenchmarkIterations)
+		if err != nil {
+			writeBadRequest(w, r, fmt.Sprintf("iterations: ожидается число от 1 до %d", service.MaxBenchmarkIterations))
+			return
+		}
+		concurrency, err := queryInt(r, "concurrency", 1)
+		if err != nil {
+			writeBadRequest(w, r, fmt.Sprintf("concurrency: ожидается число от 1 до %d", service.MaxBenchmarkConcurrency))
+			return
+		}
+
+		result, err := orderService.RunBenchmark(r.Context(), orderUID, service.BenchmarkOptions{
+			Iterations:  iterations,
+			Concurrency: concurrency,
+		})
+		if err != nil {
+			writeError(w, r, err, map[string]interface{}{"order_uid": orderUID})
+			return
+		}
+
+		log.Printf("Бенчмарк %s: %d запросов в %d потоков, p99 кэш %.3f мс, БД %.3f мс",
+			orderUID, iterations, concurrency, result.Cache.P99Ms, result.DB.P99Ms)
+		writeJSON(w, http.StatusOK, result)
+	}
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+
+	// ответы меньше этого размера отправляются без сжатия
+	compressMinSize = 1024
+	// уровень brotli для динамических ответов: заметно быстрее уровня по умолчанию
+	brotliLevel = 4
+)
+
+// поддерживаемые кодировки в порядке предпочтения при равном q
+var supportedEncodings = []string{encodingBrotli, encodingGzip}
+
+var encoderPools = map[string]*sync.Pool{
+	encodingBrotli: {New: func() interface{} { return brotli.NewWriterLevel(io.Discard, brotliLevel) }},
+	encodingGzip:   {New: func() interface{} { return gzip.NewWriter(io.Discard) }},
+}
+
+// сжимаемый кодер, который можно переиспользовать через Reset
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// сжимает ответы brotli или gzip согласно Accept-Encoding; уже сжатые, потоковые SSE,
+// WebSocket и короткие ответы передаются как есть
+func withCompression(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// ETag сжатого ответа отличается суффиксом кодировки, обработчики сравнивают исходный
+		suffixed := false
+		if inm := r.Header.Get("If-None-Match"); inm != "" {
+			stripped := strings.ReplaceAll(inm, "-"+encoding+`"`, `"`)
+			suffixed = stripped != inm
+			r.Header.Set("If-None-Match", stripped)
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, suffixed: suffixed, head: r.Method == http.MethodHead}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// выбирает кодировку с наибольшим q; q=0 запрещает кодировку, * относится к неперечисленным
+func negotiateEncoding(accept string) string {
+	if accept == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if name == "*" {
+			wildcard = q
+		} else {
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range supportedEncodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// откладывает решение о сжатии до первых compressMinSize байт или Flush
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	// клиент прислал If-None-Match с ETag сжатого ответа
+	suffixed bool
+	head     bool
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.status = status
+	cw.wroteHeader = true
+
+	// у этих ответов нет тела, решать нечего
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified || cw.head {
+		if status == http.StatusNotModified && cw.suffixed {
+			cw.suffixETag()
+		}
+		cw.decided = true
+		cw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= compressMinSize {
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// начинает ответ со сжатием или без и отправляет накопленные байты
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+	header := cw.Header()
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		// иначе net/http определил бы тип по уже сжатым байтам
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if cw.compressible() {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", cw.encoding)
+		cw.suffixETag()
+		cw.enc = encoderPools[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.enc != nil {
+		_, err := cw.enc.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+func (cw *compressWriter) compressible() bool {
+	header := cw.Header()
+	// диапазоны (206) относятся к несжатому представлению
+	if cw.status != http.StatusOK && cw.status != http.StatusCreated {
+		return false
+	}
+	if len(cw.buf) < compressMinSize || header.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	switch {
+	case mediaType == "text/event-stream":
+		// события должны доходить до клиента сразу и по одному
+		return false
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		mediaType == "application/x-ndjson",
+		mediaType == "application/javascript",
+		mediaType == "image/svg+xml":
+		return true
+	default:
+		return false
+	}
+}
+
+// сильный ETag должен различаться для разных кодировок: "hash" -> "hash-br"
+func (cw *compressWriter) suffixETag() {
+	etag := cw.Header().Get("ETag")
+	if strings.HasSuffix(etag, `"`) && !strings.HasPrefix(etag, "W/") {
+		cw.Header().Set("ETag", etag[:len(etag)-1]+"-"+cw.encoding+`"`)
+	}
+}
+
+func (cw *compressWriter) Flush() {
+	if cw.wroteHeader && !cw.decided {
+		cw.decide()
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// дописывает короткий ответ и завершает сжатый поток
+func (cw *compressWriter) Close() error {
+	if cw.wroteHeader && !cw.decided {
+		if err := cw.decide(); err != nil {
+			return err
+		}
+	}
+	if cw.enc == nil {
+		return nil
+	}
+	err := cw.enc.Close()
+	cw.enc.Reset(io.Discard)
+	encoderPools[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+	return err
+}
+
+// для http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"order-service/internal/database"
+	"order-service/internal/service"
+)
+
+// тело ответа с ошибкой, общее для всех обработчиков
+type errorResponse struct {
+	status int
+	// машиночитаемый код, не меняется между версиями
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// сопоставляет ошибку сервиса с HTTP статусом
+func errorResponseFor(err error) errorResponse {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return errorResponse{status: http.StatusUnprocessableEntity, Code: "validation_failed", Message: "Заказ не прошел проверку"}
+	case errors.Is(err, service.ErrEmptyMessage), errors.Is(err, service.ErrMalformedOrder):
+		return errorResponse{status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()}
+	case errors.Is(err, database.ErrOrderNotFound):
+		return errorResponse{status: http.StatusNotFound, Code: "order_not_found", Message: "Заказ с указанным ID не существует"}
+	case errors.Is(err, database.ErrWebhookNotFound):
+		return errorResponse{status: http.StatusNotFound, Code: "webhook_not_found", Message: "Подписка или доставка с указанным ID не существует"}
+	case errors.Is(err, database.ErrInvalidArgument):
+		return errorResponse{status: http.StatusBadRequest, Code: "invalid_parameter", Message: err.Error()}
+	case errors.Is(err, database.ErrInvalidCursor):
+		return errorResponse{status: http.StatusBadRequest, Code: "invalid_cursor", Message: "Курсор поврежден или не соответствует сортировке"}
+	case errors.Is(err, database.ErrDuplicateOrder):
+		return errorResponse{status: http.StatusConflict, Code: "order_exists", Message: "Заказ с указанным ID уже существует"}
+	case errors.Is(err, database.ErrNotFound):
+		return errorResponse{status: http.StatusNotFound, Code: "not_found", Message: "Запись не найдена"}
+	case errors.Is(err, database.ErrConflict):
+		return errorResponse{status: http.StatusConflict, Code: "conflict", Message: "Запись с такими данными уже существует"}
+	case errors.Is(err, database.ErrUnavailable):
+		return errorResponse{status: http.StatusServiceUnavailable, Code: "unavailable", Message: "База данных временно недоступна"}
+	default:
+		return errorResponse{status: http.StatusInternalServerError, Code: "internal", Message: "Внутренняя ошибка сервера"}
+	}
+}
+
+// пишет ошибку сервиса в едином формате; details дополняют описание ошибки
+func writeError(w http.ResponseWriter, r *http.Request, err error, details map[string]interface{}) {
+	response := errorResponseFor(err)
+	if response.status >= http.StatusInternalServerError {
+		log.Printf("Ошибка обработки %s %s [%s]: %v", r.Method, r.URL.Path, requestID(r), err)
+	}
+	if response.status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "5")
+	}
+
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		if details == nil {
+			details = make(map[string]interface{})
+		}
+		details["errors"] = validationErr.Fields
+	}
+	writeAPIError(w, r, response.status, response.Code, response.Message, details)
+}
+
+// пишет ошибку, не связанную с сервисом: неверный параметр, метод, авторизация
+func writeAPIError(w http.ResponseWriter, r *http.Request, status int, code, message string, details map[string]interface{}) {
+	writeJSON(w, status, errorResponse{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: requestID(r),
+	})
+}
+
+// неверный параметр запроса
+func writeBadRequest(w http.ResponseWriter, r *http.Request, message string) {
+	writeAPIError(w, r, http.StatusBadRequest, "invalid_parameter", message, nil)
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"order-service/internal/database"
+	"order-service/internal/export"
+	"order-service/internal/service"
+	"time"
+)
+
+// GET /api/v1/orders/export?format=csv&gzip=true&customer_id=...
+// принимает те же фильтры, что и поиск; limit по умолчанию не ограничен
+func ordersExportHandler(orderService service.OrderService, piiPolicy PIIPolicy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseSearchFilter(r, 0, 0)
+		if err != nil {
+			writeError(w, r, fmt.Errorf("%w: %w", database.ErrInvalidArgument, err), nil)
+			return
+		}
+		if filter.Cursor != "" {
+			writeError(w, r, fmt.Errorf("%w: cursor не поддерживается выгрузкой", database.ErrInvalidArgument), nil)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = export.FormatNDJSON
+		}
+		compress := r.URL.Query().Get("gzip") == "true"
+
+		writer, err := export.NewOrderWriter(w, format, compress)
+		if err != nil {
+			writeError(w, r, fmt.Errorf("%w: %w", database.ErrInvalidArgument, err), nil)
+			return
+		}
+
+		filename := fmt.Sprintf("orders-%s.%s", time.Now().Format("20060102-150405"), export.FileExtension(format, compress))
+		w.Header().Set("Content-Type", export.ContentType(format, compress))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+		masked := piiPolicy.masked(w, r)
+		count := 0
+		err = orderService.ExportOrders(r.Context(), filter, func(order database.Order) error {
+			count++
+			if masked {
+				order = piiPolicy.Masker.MaskOrder(order)
+			}
+			return writer.WriteOrder(order)
+		})
+
+		// до первого заказа в ответ ничего не записано, можно вернуть обычную ошибку
+		if err != nil && count == 0 {
+			w.Header().Del("Content-Disposition")
+			writeError(w, r, err, nil)
+			return
+		}
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+
+		// заголовки уже отправлены, поэтому об ошибке сообщаем только в лог: файл будет обрезан
+		if err != nil {
+			log.Printf("Выгрузка заказов прервана после %d заказов: %v", count, err)
+			return
+		}
+		log.Printf("Выгружено заказов: %d (%s)", count, filename)
+	}
+}
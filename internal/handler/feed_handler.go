@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"order-service/internal/feed"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// интервал комментария-пульса SSE, чтобы прокси не закрывали соединение
+	feedHeartbeatInterval = 15 * time.Second
+	// время на запись одного сообщения WebSocket, дольше - клиент считается зависшим
+	feedWriteTimeout = 10 * time.Second
+	// ping WebSocket и ожидание pong
+	feedPingInterval = 30 * time.Second
+	feedPongTimeout  = 60 * time.Second
+)
+
+func feedFilter(r *http.Request) feed.Filter {
+	query := r.URL.Query()
+	return feed.Filter{
+		Entry:           query.Get("entry"),
+		DeliveryService: query.Get("delivery_service"),
+	}
+}
+
+// GET /api/v1/orders/stream?entry=&delivery_service= - лента новых заказов в формате SSE
+func ordersStreamHandler(hub feed.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeAPIError(w, r, http.StatusInternalServerError, "streaming_unsupported", "Соединение не поддерживает потоковую передачу", nil)
+			return
+		}
+
+		sub := hub.Subscribe(feedFilter(r))
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "retry: 3000\n\n")
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(feedHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-sub.Done():
+				if sub.Lagged() {
+					fmt.Fprint(w, "event: lagged\ndata: {}\n\n")
+					flusher.Flush()
+				}
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+			case event := <-sub.Events():
+				data, err := json.Marshal(event)
+				if err != nil {
+					log.Printf("Ошибка сериализации события ленты: %v", err)
+					continue
+				}
+				fmt.Fprintf(w, "id: %d\nevent: order\ndata: %s\n\n", event.ID, data)
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// сообщение ленты по WebSocket
+type feedMessage struct {
+	Type  string      `json:"type"`
+	Order *feed.Event `json:"order,omitempty"`
+}
+
+// GET /api/v1/orders/ws?entry=&delivery_service= - лента новых заказов по WebSocket
+func ordersWebSocketHandler(hub feed.Hub, cors corsPolicy) http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 4096,
+		// браузер отправляет WebSocket без проверки CORS, источник проверяем сами
+		CheckOrigin: cors.checkOrigin,
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade уже ответил клиенту ошибкой
+			return
+		}
+		defer conn.Close()
+
+		sub := hub.Subscribe(feedFilter(r))
+		defer sub.Close()
+
+		// клиент ничего не присылает, чтение нужно для pong и обнаружения закрытия
+		closed := make(chan struct{})
+		conn.SetReadDeadline(time.Now().Add(feedPongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(feedPongTimeout))
+		})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		ping := time.NewTicker(feedPingInterval)
+		defer ping.Stop()
+
+		for {
+			var err error
+			select {
+			case <-closed:
+				return
+			case <-sub.Done():
+				reason := "server shutdown"
+				if sub.Lagged() {
+					reason = "lagged"
+				}
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, reason),
+					time.Now().Add(feedWriteTimeout))
+				return
+			case <-ping.C:
+				err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(feedWriteTimeout))
+			case event := <-sub.Events():
+				conn.SetWriteDeadline(time.Now().Add(feedWriteTimeout))
+				err = conn.WriteJSON(feedMessage{Type: "order", Order: &event})
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
@@ -1,18 +1,44 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"order-service/internal/auth"
+	"order-service/internal/cache"
+	"order-service/internal/config"
 	"order-service/internal/database"
+	"order-service/internal/feed"
+	"order-service/internal/openapi"
+	"order-service/internal/pii"
+	"order-service/internal/service"
+	"order-service/internal/webhook"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/websocket"
 )
 
 // простой mock сервиса, реализующий интерфейс service.OrderService
 type MockOrderService struct {
 	orders map[string]database.Order
+	warmup cache.WarmupProgress
+	// если задана, возвращается из методов поиска вместо результата
+	err error
+	// последний фильтр, переданный в SearchOrders
+	searchFilter database.OrderSearchFilter
+	// последний фильтр, переданный в методы аналитики
+	analyticsFilter database.AnalyticsFilter
 }
 
 func NewMockOrderService() *MockOrderService {
@@ -42,40 +68,224 @@ func NewMockOrderService() *MockOrderService {
 }
 
 func (m *MockOrderService) GetOrder(orderUID string) (database.Order, error) {
+	if m.err != nil {
+		return database.Order{}, m.err
+	}
 	order, exists := m.orders[orderUID]
 	if !exists {
-		return database.Order{}, fmt.Errorf("Заказ не найден!")
+		return database.Order{}, fmt.Errorf("заказ %s: %w", orderUID, database.ErrOrderNotFound)
 	}
 	return order, nil
 }
 
+func (m *MockOrderService) GetOrdersByTrackNumber(trackNumber string) ([]database.Order, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	orders := []database.Order{}
+	for _, order := range m.orders {
+		if order.TrackNumber == trackNumber {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+func (m *MockOrderService) GetOrdersByCustomerID(customerID string, limit int) ([]database.Order, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	orders := []database.Order{}
+	for _, order := range m.orders {
+		if order.CustomerID == customerID {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+// сохраняет заказ в памяти; заказ без track_number считается невалидным
 func (m *MockOrderService) ProcessOrder(message []byte) error {
-	return nil
+	_, err := m.SubmitOrder(message)
+	return err
+}
+
+func (m *MockOrderService) SubmitOrder(message []byte) (database.Order, error) {
+	order, err := m.CheckOrder(message)
+	if err != nil {
+		return order, err
+	}
+	if _, exists := m.orders[order.OrderUID]; exists {
+		return order, fmt.Errorf("ошибка сохранения заказа: %w", database.ErrDuplicateOrder)
+	}
+	m.orders[order.OrderUID] = order
+	return order, nil
+}
+
+func (m *MockOrderService) ParseOrder(message []byte) (database.Order, error) {
+	var order database.Order
+	if err := json.Unmarshal(message, &order); err != nil {
+		return order, fmt.Errorf("%w: %w", service.ErrMalformedOrder, err)
+	}
+	if order.TrackNumber == "" {
+		return order, &service.ValidationError{Fields: []service.FieldError{
+			{Field: "track_number", Rule: "required", Message: "поле 'track_number' обязательно для заполнения"},
+		}}
+	}
+	return order, nil
+}
+
+func (m *MockOrderService) CheckOrder(message []byte) (database.Order, error) {
+	return m.ParseOrder(message)
 }
 
 func (m *MockOrderService) ValidateOrder(order database.Order) error {
 	return nil
 }
 
+func (m *MockOrderService) SearchOrders(ctx context.Context, filter database.OrderSearchFilter) (database.OrderSearchResult, error) {
+	m.searchFilter = filter
+	if m.err != nil {
+		return database.OrderSearchResult{}, m.err
+	}
+	result := database.OrderSearchResult{Orders: []database.Order{}}
+	for _, order := range m.orders {
+		if filter.CustomerID == "" || order.CustomerID == filter.CustomerID {
+			result.Orders = append(result.Orders, order)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockOrderService) ImportOrders(ctx context.Context, orders []database.Order) (service.ImportResult, error) {
+	return service.ImportResult{}, nil
+}
+
+func (m *MockOrderService) ExportOrders(ctx context.Context, filter database.OrderSearchFilter, fn func(order database.Order) error) error {
+	m.searchFilter = filter
+	if m.err != nil {
+		return m.err
+	}
+	for _, order := range m.orders {
+		if filter.CustomerID != "" && order.CustomerID != filter.CustomerID {
+			continue
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockOrderService) SearchOrdersText(ctx context.Context, text string, limit, offset int) ([]database.OrderTextMatch, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	matches := []database.OrderTextMatch{}
+	for _, order := range m.orders {
+		for _, item := range order.Items {
+			if strings.Contains(item.Name, text) {
+				matches = append(matches, database.OrderTextMatch{
+					Order:    order,
+					Rank:     1,
+					Snippets: []string{strings.ReplaceAll(item.Name, text, "<mark>"+text+"</mark>")},
+				})
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+func (m *MockOrderService) GetSalesStats(ctx context.Context, filter database.AnalyticsFilter) ([]database.SalesPoint, error) {
+	m.analyticsFilter = filter
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []database.SalesPoint{{Orders: int64(len(m.orders)), Revenue: 100, AvgBasket: 100}}, nil
+}
+
+func (m *MockOrderService) GetTopBrands(ctx context.Context, filter database.AnalyticsFilter, limit int) ([]database.TopEntry, error) {
+	m.analyticsFilter = filter
+	return []database.TopEntry{{Brand: "Test Brand", Items: 1, Orders: 1, Revenue: 100}}, m.err
+}
+
+func (m *MockOrderService) GetTopProducts(ctx context.Context, filter database.AnalyticsFilter, limit int) ([]database.TopEntry, error) {
+	m.analyticsFilter = filter
+	return []database.TopEntry{{Brand: "Test Brand", NmID: 1, Name: "Test Item", Items: 1, Orders: 1, Revenue: 100}}, m.err
+}
+
 func (m *MockOrderService) GetCacheSize() int {
 	return len(m.orders)
 }
 
+func (m *MockOrderService) GetCacheStats() cache.CacheStats {
+	return cache.CacheStats{Size: len(m.orders), Hits: 3, Misses: 1, HitRatio: 0.75}
+}
+
+func (m *MockOrderService) GetWarmupProgress() cache.WarmupProgress {
+	return m.warmup
+}
+
 func (m *MockOrderService) CheckDBConnection() error {
 	return nil
 }
 
-func (m *MockOrderService) RunBenchmark(orderUID string) (map[string]time.Duration, error) {
-	return map[string]time.Duration{"cache": time.Millisecond, "db": time.Second}, nil
+func (m *MockOrderService) RunBenchmark(ctx context.Context, orderUID string, opts service.BenchmarkOptions) (service.BenchmarkResult, error) {
+	if _, exists := m.orders[orderUID]; !exists {
+		return service.BenchmarkResult{}, fmt.Errorf("заказ %s: %w", orderUID, database.ErrOrderNotFound)
+	}
+	if opts.Concurrency > service.MaxBenchmarkConcurrency {
+		return service.BenchmarkResult{}, fmt.Errorf("%w: concurrency", database.ErrInvalidArgument)
+	}
+	return service.BenchmarkResult{
+		OrderUID:    orderUID,
+		Iterations:  opts.Iterations,
+		Concurrency: opts.Concurrency,
+		Cache:       service.LatencyStats{Requests: opts.Iterations, P50Ms: 0.01, P99Ms: 0.05},
+		DB:          service.LatencyStats{Requests: opts.Iterations, P50Ms: 1, P99Ms: 5},
+		Speedup:     100,
+	}, nil
+}
+
+func (m *MockOrderService) ListCacheEntries(offset, limit int) ([]service.CacheEntryInfo, int) {
+	var entries []service.CacheEntryInfo
+	for orderUID := range m.orders {
+		entries = append(entries, service.CacheEntryInfo{OrderUID: orderUID})
+	}
+	return entries, len(entries)
+}
+
+func (m *MockOrderService) EvictCacheEntry(orderUID string) bool {
+	_, exists := m.orders[orderUID]
+	delete(m.orders, orderUID)
+	return exists
+}
+
+func (m *MockOrderService) FlushCache() int {
+	count := len(m.orders)
+	m.orders = map[string]database.Order{}
+	return count
+}
+
+func (m *MockOrderService) WarmCacheOrders(orderUIDs []string) service.CacheWarmResult {
+	return service.CacheWarmResult{Warmed: orderUIDs}
 }
 
 func (m *MockOrderService) PrintCacheContents() {
 	// пустая реализация для тестов
 }
 
+// оборачивает обработчик в маршрутизатор, чтобы заполнить параметры пути
+func withRoute(method, pattern string, handler http.HandlerFunc) http.HandlerFunc {
+	router := NewRouter()
+	router.Handle(method, pattern, handler)
+	return router.ServeHTTP
+}
+
 func TestOrderHandlerFound(t *testing.T) {
 	service := NewMockOrderService()
-	handler := orderHandler(service)
+	handler := withRoute("GET", "/order/{id}", orderHandler(service, PIIPolicy{}))
 
 	// создаем тестовый запрос
 	req := httptest.NewRequest("GET", "/order/found123", nil)
@@ -106,7 +316,7 @@ func TestOrderHandlerFound(t *testing.T) {
 
 func TestOrderHandlerNotFound(t *testing.T) {
 	service := NewMockOrderService()
-	handler := orderHandler(service)
+	handler := withRoute("GET", "/order/{id}", orderHandler(service, PIIPolicy{}))
 
 	// Запрос несуществующего заказа
 	req := httptest.NewRequest("GET", "/order/notfound999", nil)
@@ -126,8 +336,262 @@ func TestOrderHandlerNotFound(t *testing.T) {
 		t.Fatalf("Ошибка декодирования JSON: %v", err)
 	}
 
-	if response["error"] != "Order not found" {
-		t.Errorf("Ожидалась ошибка 'Order not found', получено '%v'", response["error"])
+	if response["code"] != "order_not_found" {
+		t.Errorf("Ожидался код 'order_not_found', получено '%v'", response["code"])
+	}
+}
+
+func TestOrderHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"БД недоступна", fmt.Errorf("заказ: %w", database.ErrUnavailable), http.StatusServiceUnavailable},
+		{"внутренняя ошибка", fmt.Errorf("ошибка сканирования заказа"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewMockOrderService()
+			service.err = tt.err
+			handler := withRoute("GET", "/order/{id}", orderHandler(service, PIIPolicy{}))
+
+			req := httptest.NewRequest("GET", "/order/found123", nil)
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Ожидался статус %d, получен %d", tt.status, w.Code)
+			}
+
+			var response struct {
+				Code    string                 `json:"code"`
+				Details map[string]interface{} `json:"details"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Ошибка декодирования JSON: %v", err)
+			}
+			if response.Code == "" || response.Details["order_uid"] != "found123" {
+				t.Errorf("Неверное тело ошибки: %+v", response)
+			}
+		})
+	}
+}
+
+func TestErrorCodesNameResource(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("заказ: %w", database.ErrOrderNotFound), http.StatusNotFound, "order_not_found"},
+		{fmt.Errorf("подписка 1: %w", database.ErrWebhookNotFound), http.StatusNotFound, "webhook_not_found"},
+		{fmt.Errorf("ошибка сохранения заказа: %w", database.ErrDuplicateOrder), http.StatusConflict, "order_exists"},
+		// конфликт или отсутствие записи другого ресурса не выдаются за ошибку заказа
+		{fmt.Errorf("ошибка сохранения подписки: %w", database.ErrConflict), http.StatusConflict, "conflict"},
+		{fmt.Errorf("ошибка поиска: %w", database.ErrNotFound), http.StatusNotFound, "not_found"},
+	}
+
+	for _, tt := range tests {
+		if response := errorResponseFor(tt.err); response.status != tt.status || response.Code != tt.code {
+			t.Errorf("Для %v ожидались %d %s, получены %d %s", tt.err, tt.status, tt.code, response.status, response.Code)
+		}
+	}
+	if !errors.Is(database.ErrOrderNotFound, database.ErrNotFound) || !errors.Is(database.ErrDuplicateOrder, database.ErrConflict) {
+		t.Error("Ошибки заказа должны оборачивать общие ErrNotFound и ErrConflict")
+	}
+}
+
+func TestOrdersSearchHandler(t *testing.T) {
+	service := NewMockOrderService()
+	handler := ordersSearchHandler(service, PIIPolicy{})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/v1/orders?brand=Nike&sort=amount&min_amount=100&date_to=2024-01-31&limit=10", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+
+	filter := service.searchFilter
+	if filter.Brand != "Nike" || filter.SortBy != database.SortByAmount || filter.Desc || filter.MinAmount != 100 || filter.Limit != 10 {
+		t.Errorf("Неверно разобран фильтр: %+v", filter)
+	}
+	// дата верхней границы включается целиком
+	if want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC); !filter.CreatedTo.Equal(want) {
+		t.Errorf("Ожидалась граница %v, получена %v", want, filter.CreatedTo)
+	}
+
+	for _, query := range []string{"sort=name", "limit=0", "min_amount=abc", "date_from=yesterday"} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/api/v1/orders?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Для %s ожидался статус 400, получен %d", query, w.Code)
+		}
+	}
+
+	service.err = fmt.Errorf("ошибка поиска заказов: %w", database.ErrInvalidCursor)
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/v1/orders?cursor=broken", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Для неверного курсора ожидался статус 400, получен %d", w.Code)
+	}
+}
+
+func TestOrdersTextSearchHandler(t *testing.T) {
+	service := NewMockOrderService()
+	handler := ordersTextSearchHandler(service, PIIPolicy{})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/v1/orders/search?q=Test", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+
+	var response struct {
+		Count   int                       `json:"count"`
+		Results []database.OrderTextMatch `json:"results"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Ошибка декодирования JSON: %v", err)
+	}
+	if response.Count != 1 || response.Results[0].Order.OrderUID != "found123" {
+		t.Errorf("Неверные результаты поиска: %+v", response)
+	}
+	if len(response.Results[0].Snippets) == 0 || !strings.Contains(response.Results[0].Snippets[0], "<mark>Test</mark>") {
+		t.Errorf("Ожидался подсвеченный фрагмент, получено %v", response.Results[0].Snippets)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/v1/orders/search?q=+", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Для пустого запроса ожидался статус 400, получен %d", w.Code)
+	}
+}
+
+func TestOrdersExportHandler(t *testing.T) {
+	service := NewMockOrderService()
+	handler := ordersExportHandler(service, PIIPolicy{})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/v1/orders/export?format=csv", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Errorf("Неверный Content-Type: %s", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), ".csv") {
+		t.Errorf("Неверный Content-Disposition: %s", w.Header().Get("Content-Disposition"))
+	}
+	// по умолчанию выгрузка не ограничена
+	if service.searchFilter.Limit != 0 {
+		t.Errorf("Ожидался limit 0, получен %d", service.searchFilter.Limit)
+	}
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "found123,") {
+		t.Errorf("Ожидались заголовок и строка товара, получено %q", lines)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/v1/orders/export?format=xml", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Для неизвестного формата ожидался статус 400, получен %d", w.Code)
+	}
+
+	// ошибка до первого заказа возвращается обычным ответом
+	service.err = fmt.Errorf("ошибка выгрузки заказов: %w", database.ErrUnavailable)
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/v1/orders/export", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("Ожидался статус 503 без вложения, получен %d", w.Code)
+	}
+}
+
+func TestAnalyticsHandlers(t *testing.T) {
+	service := NewMockOrderService()
+
+	w := httptest.NewRecorder()
+	salesAnalyticsHandler(service)(w, httptest.NewRequest("GET", "/api/v1/analytics/sales?group_by=currency&date_from=2024-01-01", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	// по умолчанию группировка по дням
+	if service.analyticsFilter.Interval != database.IntervalDay || service.analyticsFilter.Dimension != "currency" || service.analyticsFilter.From.IsZero() {
+		t.Errorf("Неверно разобран фильтр: %+v", service.analyticsFilter)
+	}
+
+	w = httptest.NewRecorder()
+	topAnalyticsHandler(service, true)(w, httptest.NewRequest("GET", "/api/v1/analytics/top-products?limit=5", nil))
+	var response struct {
+		Top []database.TopEntry `json:"top"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Ошибка декодирования JSON: %v", err)
+	}
+	if len(response.Top) != 1 || response.Top[0].NmID != 1 {
+		t.Errorf("Неверный топ товаров: %+v", response.Top)
+	}
+	// без interval топ строится за весь период
+	if service.analyticsFilter.Interval != "" {
+		t.Errorf("Для топа не должен подставляться интервал: %+v", service.analyticsFilter)
+	}
+
+	// interval и group_by передаются в репозиторий для топа по периодам и измерению
+	w = httptest.NewRecorder()
+	topAnalyticsHandler(service, false)(w, httptest.NewRequest("GET", "/api/v1/analytics/top-brands?limit=3&interval=month&group_by=region", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	if service.analyticsFilter.Interval != database.IntervalMonth || service.analyticsFilter.Dimension != "region" {
+		t.Errorf("Неверно разобран фильтр топа: %+v", service.analyticsFilter)
+	}
+
+	w = httptest.NewRecorder()
+	topAnalyticsHandler(service, false)(w, httptest.NewRequest("GET", "/api/v1/analytics/top-brands?limit=1000", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Для слишком большого limit ожидался статус 400, получен %d", w.Code)
+	}
+
+	// неверное измерение отклоняет репозиторий
+	service.err = fmt.Errorf("ошибка получения статистики продаж: %w", database.ErrInvalidArgument)
+	w = httptest.NewRecorder()
+	salesAnalyticsHandler(service)(w, httptest.NewRequest("GET", "/api/v1/analytics/sales?group_by=color", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Для неверного измерения ожидался статус 400, получен %d", w.Code)
+	}
+}
+
+func TestOrdersByTrackHandler(t *testing.T) {
+	service := NewMockOrderService()
+	handler := withRoute("GET", "/api/v1/tracks/{track}/orders", ordersByTrackHandler(service, PIIPolicy{}))
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/v1/tracks/FOUND_TRACK/orders", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получен %d", w.Code)
+	}
+
+	var response struct {
+		Count  int              `json:"count"`
+		Orders []database.Order `json:"orders"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Ошибка декодирования JSON: %v", err)
+	}
+	if response.Count != 1 || response.Orders[0].OrderUID != "found123" {
+		t.Errorf("Ожидался заказ found123, получено %+v", response.Orders)
+	}
+
+	// некорректный лимит для поиска по покупателю
+	w = httptest.NewRecorder()
+	withRoute("GET", "/api/v1/customers/{id}/orders", ordersByCustomerHandler(service, PIIPolicy{}))(w,
+		httptest.NewRequest("GET", "/api/v1/customers/c1/orders?limit=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400, получен %d", w.Code)
 	}
 }
 
@@ -153,6 +617,14 @@ func TestCacheHandler(t *testing.T) {
 	if response["cache_size"] != float64(1) {
 		t.Errorf("Ожидался размер кэша 1, получен %v", response["cache_size"])
 	}
+
+	stats, ok := response["stats"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Статистика кэша отсутствует в ответе: %v", response)
+	}
+	if stats["hits"] != float64(3) || stats["misses"] != float64(1) {
+		t.Errorf("Ожидались hits=3 и misses=1, получено %v", stats)
+	}
 }
 
 func TestHealthHandler(t *testing.T) {
@@ -181,3 +653,890 @@ func TestHealthHandler(t *testing.T) {
 		t.Errorf("Ожидался размер кэша 1, получен %v", response["cache_size"])
 	}
 }
+
+func TestReadyHandler(t *testing.T) {
+	service := NewMockOrderService()
+	handler := readyHandler(service)
+
+	// пока кэш прогревается, сервис не готов
+	service.warmup = cache.WarmupProgress{State: cache.WarmupRunning, Loaded: 40, Limit: 100}
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/ready", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Ожидался статус 503, получен %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Ошибка декодирования JSON: %v", err)
+	}
+	progress, _ := response["cache_warmup"].(map[string]interface{})
+	if progress["loaded"] != float64(40) {
+		t.Errorf("Ожидался прогресс 40, получен %v", progress["loaded"])
+	}
+
+	// после прогрева сервис готов
+	service.warmup = cache.WarmupProgress{State: cache.WarmupCompleted, Loaded: 100, Limit: 100}
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/ready", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получен %d", w.Code)
+	}
+}
+
+// аутентификатор с ключами reader-key, support-key и secret (admin)
+func newTestAuthenticator(t *testing.T) auth.Authenticator {
+	t.Helper()
+	authn, err := auth.NewAuthenticator(auth.Options{StaticKeys: []auth.StaticKey{
+		{Name: "reader", Role: auth.RoleReader, Hash: auth.HashKey("reader-key")},
+		{Name: "support", Role: auth.RoleSupport, Hash: auth.HashKey("support-key")},
+		{Name: "admin", Role: auth.RoleAdmin, Hash: auth.HashKey("secret")},
+	}})
+	if err != nil {
+		t.Fatalf("Ошибка создания аутентификатора: %v", err)
+	}
+	return authn
+}
+
+func TestRequireRole(t *testing.T) {
+	service := NewMockOrderService()
+	handler := requireRole(newTestAuthenticator(t), auth.RoleAdmin, cacheFlushHandler(service))
+
+	serve := func(header http.Header) (*httptest.ResponseRecorder, errorResponse) {
+		req := httptest.NewRequest("POST", "/admin/cache/flush", nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		var apiErr errorResponse
+		json.NewDecoder(bytes.NewReader(w.Body.Bytes())).Decode(&apiErr)
+		return w, apiErr
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		status int
+		code   string
+	}{
+		{"без ключа", nil, http.StatusUnauthorized, "unauthorized"},
+		{"неизвестный ключ", http.Header{"X-Api-Key": {"wrong"}}, http.StatusUnauthorized, "invalid_credentials"},
+		{"неверный JWT", http.Header{"Authorization": {"Bearer a.b.c"}}, http.StatusUnauthorized, "invalid_credentials"},
+		{"роль reader", http.Header{"X-Api-Key": {"reader-key"}}, http.StatusForbidden, "forbidden"},
+		{"роль support", http.Header{"Authorization": {"Bearer support-key"}}, http.StatusForbidden, "forbidden"},
+	}
+	for _, tt := range tests {
+		w, apiErr := serve(tt.header)
+		if w.Code != tt.status || apiErr.Code != tt.code {
+			t.Errorf("%s: ожидался %d %s, получен %d %s", tt.name, tt.status, tt.code, w.Code, apiErr.Code)
+		}
+		if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: нет заголовка WWW-Authenticate", tt.name)
+		}
+	}
+	if service.GetCacheSize() != 1 {
+		t.Error("Кэш очищен без нужной роли")
+	}
+
+	// ключ администратора принимается и в X-API-Key, и как Bearer
+	w, _ := serve(http.Header{"Authorization": {"Bearer secret"}})
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получен %d", w.Code)
+	}
+	var response map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&response)
+	if response["flushed"] != float64(1) {
+		t.Errorf("Ожидалось удаление 1 записи, получено %v", response["flushed"])
+	}
+
+	// обработчик видит клиента в контексте
+	var principal auth.Principal
+	whoami := requireRole(newTestAuthenticator(t), auth.RoleReader, func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = auth.PrincipalFrom(r.Context())
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-API-Key", "support-key")
+	whoami(httptest.NewRecorder(), req)
+	if principal.Subject != "support" || principal.Role != auth.RoleSupport || principal.Method != auth.MethodAPIKey {
+		t.Errorf("Неверный клиент в контексте: %+v", principal)
+	}
+	// потоковые маршруты принимают ключ параметром, дальше он не передается
+	var rawQuery string
+	stream := withQueryToken(requireRole(newTestAuthenticator(t), auth.RoleReader, func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = auth.PrincipalFrom(r.Context())
+		rawQuery = r.URL.RawQuery
+	}))
+	stream(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/orders/stream?entry=WBIL&access_token=reader-key", nil))
+	if principal.Subject != "reader" || rawQuery != "entry=WBIL" {
+		t.Errorf("access_token не принят: клиент %+v, запрос %q", principal, rawQuery)
+	}
+}
+
+func TestCORSPolicy(t *testing.T) {
+	handler := enableCORS(corsPolicy{origins: []string{"https://shop.example"}}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/orders/found123", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w := serve("OPTIONS", "https://shop.example")
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://shop.example" {
+		t.Errorf("Разрешенный источник не получил CORS заголовки: %v", w.Header())
+	}
+	if !strings.Contains(w.Header().Get("Access-Control-Allow-Headers"), "X-API-Key") {
+		t.Errorf("Нет X-API-Key в Access-Control-Allow-Headers")
+	}
+	if w := serve("GET", "https://evil.example"); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Неразрешенный источник получил CORS заголовки")
+	}
+
+	// WebSocket: тот же хост и разрешенный источник
+	policy := corsPolicy{origins: []string{"https://shop.example"}}
+	for origin, allowed := range map[string]bool{
+		"":                     true,
+		"http://example.com":   true,
+		"https://shop.example": true,
+		"https://evil.example": false,
+	} {
+		req := httptest.NewRequest("GET", "http://example.com/api/v1/orders/ws", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if policy.checkOrigin(req) != allowed {
+			t.Errorf("Origin %q: ожидалось %v", origin, allowed)
+		}
+	}
+}
+
+func TestAdminCacheEvictAndWarm(t *testing.T) {
+	service := NewMockOrderService()
+
+	evict := withRoute("DELETE", "/admin/cache/keys/{id}", cacheEvictHandler(service))
+
+	w := httptest.NewRecorder()
+	evict(w, httptest.NewRequest("DELETE", "/admin/cache/keys/found123", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получен %d", w.Code)
+	}
+
+	// повторное удаление - заказа в кэше уже нет
+	w = httptest.NewRecorder()
+	evict(w, httptest.NewRequest("DELETE", "/admin/cache/keys/found123", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Ожидался статус 404, получен %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	body := strings.NewReader(`{"order_ids": ["a1", "b2"]}`)
+	cacheWarmHandler(service)(w, httptest.NewRequest("POST", "/admin/cache/warm", body))
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получен %d", w.Code)
+	}
+	var result struct {
+		Warmed []string `json:"warmed"`
+	}
+	json.NewDecoder(w.Body).Decode(&result)
+	if len(result.Warmed) != 2 {
+		t.Errorf("Ожидался прогрев 2 заказов, получено %v", result.Warmed)
+	}
+}
+
+// менеджер вебхуков с подписками в памяти
+type mockWebhookManager struct {
+	webhook.Manager
+	subs        []database.WebhookSubscription
+	redelivered []int64
+}
+
+func (m *mockWebhookManager) CreateSubscription(ctx context.Context, sub database.WebhookSubscription) (database.WebhookSubscription, error) {
+	if sub.URL == "" {
+		return database.WebhookSubscription{}, fmt.Errorf("%w: url обязателен", database.ErrInvalidArgument)
+	}
+	sub.ID = int64(len(m.subs) + 1)
+	sub.Active = true
+	m.subs = append(m.subs, sub)
+	return sub, nil
+}
+
+func (m *mockWebhookManager) ListSubscriptions(ctx context.Context) ([]database.WebhookSubscription, error) {
+	return m.subs, nil
+}
+
+func (m *mockWebhookManager) ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{{ID: 7, SubscriptionID: subscriptionID, Status: database.WebhookFailed}}, nil
+}
+
+func (m *mockWebhookManager) Redeliver(ctx context.Context, deliveryID int64) error {
+	if deliveryID != 7 {
+		return fmt.Errorf("доставка %d: %w", deliveryID, database.ErrWebhookNotFound)
+	}
+	m.redelivered = append(m.redelivered, deliveryID)
+	return nil
+}
+
+func TestAdminWebhooks(t *testing.T) {
+	manager := &mockWebhookManager{}
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"url": "http://partner.example/hook", "delivery_service": "meest"}`)
+	webhookCreateHandler(manager)(w, httptest.NewRequest("POST", "/admin/webhooks", body))
+	if w.Code != http.StatusCreated {
+		t.Errorf("Ожидался статус 201, получен %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	webhookCreateHandler(manager)(w, httptest.NewRequest("POST", "/admin/webhooks", strings.NewReader(`{}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для подписки без url, получен %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	withRoute("GET", "/admin/webhooks/{id}/deliveries", webhookDeliveriesHandler(manager))(w,
+		httptest.NewRequest("GET", "/admin/webhooks/1/deliveries", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получен %d", w.Code)
+	}
+	var deliveries struct {
+		Count int `json:"count"`
+	}
+	json.NewDecoder(w.Body).Decode(&deliveries)
+	if deliveries.Count != 1 {
+		t.Errorf("Ожидалась 1 доставка, получено %d", deliveries.Count)
+	}
+
+	w = httptest.NewRecorder()
+	redeliver := withRoute("POST", "/admin/webhooks/deliveries/{id}/redeliver", webhookRedeliverHandler(manager))
+	redeliver(w, httptest.NewRequest("POST", "/admin/webhooks/deliveries/7/redeliver", nil))
+	if w.Code != http.StatusAccepted || len(manager.redelivered) != 1 {
+		t.Errorf("Ожидался статус 202 и повторная доставка, получен %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	redeliver(w, httptest.NewRequest("POST", "/admin/webhooks/deliveries/8/redeliver", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Ожидался статус 404 для неизвестной доставки, получен %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	withRoute("DELETE", "/admin/webhooks/{id}", webhookDeleteHandler(manager))(w,
+		httptest.NewRequest("DELETE", "/admin/webhooks/abc", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для неверного ID, получен %d", w.Code)
+	}
+}
+
+func TestOrdersStreamHandler(t *testing.T) {
+	hub := feed.NewHub(8)
+	server := httptest.NewServer(ordersStreamHandler(hub))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?delivery_service=meest")
+	if err != nil {
+		t.Fatalf("Ошибка подключения к ленте: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Неверный Content-Type: %s", resp.Header.Get("Content-Type"))
+	}
+
+	// заказ другой службы доставки отфильтровывается
+	hub.OrderIngested(database.Order{OrderUID: "skip", DeliveryService: "cdek"})
+	hub.OrderIngested(database.Order{OrderUID: "live-1", DeliveryService: "meest"})
+
+	reader := bufio.NewReader(resp.Body)
+	var data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Ошибка чтения ленты: %v", err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+
+	var event feed.Event
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatalf("Ошибка парсинга события: %v", err)
+	}
+	if event.OrderUID != "live-1" {
+		t.Errorf("Ожидался заказ live-1, получен %s", event.OrderUID)
+	}
+}
+
+func TestOrdersWebSocketHandler(t *testing.T) {
+	hub := feed.NewHub(8)
+	server := httptest.NewServer(ordersWebSocketHandler(hub, corsPolicy{}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"?entry=WBIL", nil)
+	if err != nil {
+		t.Fatalf("Ошибка подключения по WebSocket: %v", err)
+	}
+	defer conn.Close()
+
+	// подписка создается после upgrade, ждем ее появления
+	deadline := time.Now().Add(time.Second)
+	conn.SetReadDeadline(deadline)
+	var message struct {
+		Type  string     `json:"type"`
+		Order feed.Event `json:"order"`
+	}
+	received := make(chan error, 1)
+	go func() { received <- conn.ReadJSON(&message) }()
+	for len(received) == 0 && time.Now().Before(deadline) {
+		hub.OrderIngested(database.Order{OrderUID: "ws-1", Entry: "WBIL"})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := <-received; err != nil {
+		t.Fatalf("Ошибка чтения сообщения: %v", err)
+	}
+	if message.Type != "order" || message.Order.OrderUID != "ws-1" {
+		t.Errorf("Неверное сообщение: %+v", message)
+	}
+
+	// при закрытии хаба сервер закрывает соединение
+	hub.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+				t.Errorf("Ожидалось закрытие CloseGoingAway, получено %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestOrderSubmitHandler(t *testing.T) {
+	orderService := NewMockOrderService()
+	handler := orderSubmitHandler(orderService)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/api/v1/orders", strings.NewReader(`{"order_uid": "new1", "track_number": "T1"}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получен %d", w.Code)
+	}
+	if w.Header().Get("Location") != "/api/v1/orders/new1" {
+		t.Errorf("Неверный Location: %s", w.Header().Get("Location"))
+	}
+	if _, exists := orderService.orders["new1"]; !exists {
+		t.Error("Заказ не сохранен")
+	}
+
+	// повторная отправка того же заказа
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/api/v1/orders", strings.NewReader(`{"order_uid": "new1", "track_number": "T1"}`)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Ожидался статус 409, получен %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/api/v1/orders", strings.NewReader(`{"order_uid": "new2"}`)))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Ожидался статус 422, получен %d", w.Code)
+	}
+	var response struct {
+		Code    string `json:"code"`
+		Details struct {
+			Errors []service.FieldError `json:"errors"`
+		} `json:"details"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	errs := response.Details.Errors
+	if response.Code != "validation_failed" || len(errs) != 1 || errs[0].Field != "track_number" || errs[0].Rule != "required" {
+		t.Errorf("Неверные ошибки валидации: %+v", response)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/api/v1/orders", strings.NewReader(`{"order_uid": `)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для битого JSON, получен %d", w.Code)
+	}
+}
+
+func TestOrderValidateHandler(t *testing.T) {
+	orderService := NewMockOrderService()
+	handler := orderValidateHandler(orderService)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/api/v1/orders:validate", strings.NewReader(`{"order_uid": "dry1", "track_number": "T1"}`)))
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получен %d", w.Code)
+	}
+	if _, exists := orderService.orders["dry1"]; exists {
+		t.Error("Проверка не должна сохранять заказ")
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/api/v1/orders:validate", strings.NewReader(`{"order_uid": "dry2"}`)))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Ожидался статус 422, получен %d", w.Code)
+	}
+	var response struct {
+		Details map[string]interface{} `json:"details"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if response.Details["valid"] != false {
+		t.Errorf("Ожидалось valid=false, получено %v", response.Details["valid"])
+	}
+}
+
+// отправка и проверка без сохранения применяют одни правила к одному телу
+func TestSubmitAndValidateAgree(t *testing.T) {
+	orderService := service.NewOrderService(nil, nil, nil)
+	order := `{"order_uid": "agree1", "track_number": "WBILMTESTTRACK", "entry": "WBIL", "locale": "en",
+		"customer_id": "test", "delivery_service": "meest", "shardkey": "9", "sm_id": 99,
+		"date_created": "2021-11-26T06:22:19Z", "oof_shard": "1",
+		"delivery": {"name": "Test Testov", "phone": "+9720000000", "zip": "2639809", "city": "Kiryat",
+			"address": "Ploshad Mira 15", "region": "Kraiot", "email": "test@gmail.com"},
+		"payment": {"transaction": "b563feb7-b2b8-4b6a-9f5d-123456789abc", "currency": "USD", "provider": "wbpay",
+			"amount": 1900, "payment_dt": 1637907727, "bank": "alpha", "delivery_cost": 1500, "goods_total": 300},
+		"items": [{"chrt_id": 9934930, "track_number": "WBILMTESTTRACK", "price": 453, "rid": "ab4219087a764ae0btest",
+			"name": "Mascaras", "sale": 30, "total_price": 317, "nm_id": 2389212, "brand": "Vivienne Sabo", "status": 202}]}`
+
+	payloads := map[string]string{
+		"несходящиеся суммы": order,
+		"нарушены теги":      strings.Replace(order, `"entry": "WBIL"`, `"entry": ""`, 1),
+		"битый JSON":         `{"order_uid": `,
+	}
+	for name, payload := range payloads {
+		t.Run(name, func(t *testing.T) {
+			submitted := httptest.NewRecorder()
+			orderSubmitHandler(orderService)(submitted, httptest.NewRequest("POST", "/api/v1/orders", strings.NewReader(payload)))
+			validated := httptest.NewRecorder()
+			orderValidateHandler(orderService)(validated, httptest.NewRequest("POST", "/api/v1/orders:validate", strings.NewReader(payload)))
+
+			if submitted.Code != validated.Code || submitted.Code < http.StatusBadRequest {
+				t.Fatalf("Ожидался одинаковый отказ, получены %d и %d", submitted.Code, validated.Code)
+			}
+			var submitErr, validateErr struct {
+				Code    string `json:"code"`
+				Details struct {
+					Errors []service.FieldError `json:"errors"`
+				} `json:"details"`
+			}
+			json.NewDecoder(submitted.Body).Decode(&submitErr)
+			json.NewDecoder(validated.Body).Decode(&validateErr)
+			if submitErr.Code != validateErr.Code || !slices.Equal(submitErr.Details.Errors, validateErr.Details.Errors) {
+				t.Errorf("Ошибки различаются: %+v и %+v", submitErr, validateErr)
+			}
+		})
+	}
+}
+
+func TestHTTPRouting(t *testing.T) {
+	handler := newHTTPHandler(NewMockOrderService(), nil, feed.NewHub(1), newTestAuthenticator(t), PIIPolicy{}, config.HTTPConfig{})
+	reader := http.Header{"X-Api-Key": {"reader-key"}}
+
+	serve := func(method, target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// заказ доступен по новому пути и по прежнему /order/{id}
+	for _, target := range []string{"/api/v1/orders/found123", "/order/found123"} {
+		w := serve("GET", target, reader)
+		var order database.Order
+		json.NewDecoder(w.Body).Decode(&order)
+		if w.Code != http.StatusOK || order.OrderUID != "found123" {
+			t.Errorf("%s: ожидался заказ found123, статус %d", target, w.Code)
+		}
+		if w.Header().Get("X-Request-ID") == "" {
+			t.Errorf("%s: нет заголовка X-Request-ID", target)
+		}
+	}
+
+	// литеральный путь не перекрывается параметром {id}
+	if w := serve("GET", "/api/v1/orders/search?q=Test", reader); w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200 для поиска, получен %d", w.Code)
+	}
+
+	// неподдерживаемый метод - 405 в формате API с заголовком Allow
+	w := serve("DELETE", "/api/v1/orders", http.Header{"X-Request-Id": {"req-42"}})
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Ожидался статус 405, получен %d", w.Code)
+	}
+	if w.Header().Get("Allow") != "GET, POST" {
+		t.Errorf("Ожидался Allow 'GET, POST', получен %q", w.Header().Get("Allow"))
+	}
+	var apiErr struct {
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		RequestID string `json:"request_id"`
+	}
+	json.NewDecoder(w.Body).Decode(&apiErr)
+	if apiErr.Code != "method_not_allowed" || apiErr.Message == "" || apiErr.RequestID != "req-42" {
+		t.Errorf("Неверное тело ошибки 405: %+v", apiErr)
+	}
+
+	w = serve("GET", "/api/v1/unknown", nil)
+	json.NewDecoder(w.Body).Decode(&apiErr)
+	if w.Code != http.StatusNotFound || apiErr.Code != "not_found" {
+		t.Errorf("Ожидался 404 not_found, получен %d %s", w.Code, apiErr.Code)
+	}
+
+	// маршруты требуют ключ с нужной ролью, ошибки в том же формате
+	w = serve("POST", "/admin/cache/flush", nil)
+	json.NewDecoder(w.Body).Decode(&apiErr)
+	if w.Code != http.StatusUnauthorized || apiErr.Code != "unauthorized" {
+		t.Errorf("Ожидался 401 unauthorized, получен %d %s", w.Code, apiErr.Code)
+	}
+	w = serve("DELETE", "/admin/cache/keys/found123", reader)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Ожидался статус 403 для роли reader, получен %d", w.Code)
+	}
+	w = serve("DELETE", "/admin/cache/keys/found123", http.Header{"Authorization": {"Bearer secret"}})
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200 для удаления из кэша, получен %d", w.Code)
+	}
+}
+
+// спецификация должна описывать ровно зарегистрированные маршруты
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	router := newRouter(NewMockOrderService(), &mockWebhookManager{}, feed.NewHub(1), newTestAuthenticator(t), PIIPolicy{}, config.HTTPConfig{})
+	routes := router.Routes()
+	docs := apiDocs(openapi.NewGenerator())
+
+	// ключи тестового аутентификатора от младшей роли к старшей
+	roles := []auth.Role{auth.RoleReader, auth.RoleSupport, auth.RoleAdmin}
+	keys := map[auth.Role]string{auth.RoleReader: "reader-key", auth.RoleSupport: "support-key", auth.RoleAdmin: "secret"}
+	// отмененный контекст завершает живую ленту сразу после проверки доступа
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	serve := func(route RouteInfo, role auth.Role) int {
+		target := strings.NewReplacer("{", "", "}", "").Replace(route.Pattern)
+		req := httptest.NewRequest(route.Method, target, nil).WithContext(canceled)
+		if role != "" {
+			req.Header.Set("X-API-Key", keys[role])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		key := route.Method + " " + route.Pattern
+		registered[key] = true
+		if docs[key].Summary == "" {
+			t.Errorf("Маршрут %s не описан в apiDocs", key)
+		}
+
+		// роль в описании совпадает с проверкой: без ключа закрытые маршруты отвечают 401, открытые - нет
+		role := docs[key].Role
+		if code := serve(route, ""); (role != "") != (code == http.StatusUnauthorized) {
+			t.Errorf("%s: роль в описании %q, без ключа получен статус %d", key, role, code)
+		}
+		if role == "" {
+			continue
+		}
+
+		// описанной роли достаточно, а младшей роли - нет
+		if code := serve(route, role); code == http.StatusUnauthorized || code == http.StatusForbidden {
+			t.Errorf("%s: роль в описании %q, с ее ключом получен статус %d", key, role, code)
+		}
+		if i := slices.Index(roles, role); i > 0 {
+			if code := serve(route, roles[i-1]); code != http.StatusForbidden {
+				t.Errorf("%s: роль в описании %q, с ключом %s получен статус %d", key, role, roles[i-1], code)
+			}
+		}
+	}
+	for key := range docs {
+		if !registered[key] {
+			t.Errorf("Описание %s не соответствует ни одному маршруту", key)
+		}
+	}
+
+	// документ отдается по HTTP и содержит все маршруты и схемы заказа
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	var spec openapi.Document
+	if err := json.NewDecoder(w.Body).Decode(&spec); err != nil {
+		t.Fatalf("Ошибка декодирования спецификации: %v", err)
+	}
+
+	operations := make(map[string]bool)
+	for path, item := range spec.Paths {
+		for method := range item {
+			operations[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	if len(operations) != len(registered) {
+		t.Errorf("В спецификации %d операций, зарегистрировано маршрутов %d", len(operations), len(registered))
+	}
+	for key := range registered {
+		if !operations[key] {
+			t.Errorf("Маршрут %s отсутствует в спецификации", key)
+		}
+	}
+
+	for _, name := range []string{"Order", "Delivery", "Payment", "Item", "Error"} {
+		if spec.Components.Schemas[name] == nil {
+			t.Errorf("Нет схемы %s", name)
+		}
+	}
+	phone := spec.Components.Schemas["Delivery"].Properties["phone"]
+	if phone == nil || phone.Pattern == "" {
+		t.Errorf("Ожидался pattern из e164 для delivery.phone: %+v", phone)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/docs", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/api/openapi.json") {
+		t.Errorf("Страница документации не отдана: %d", w.Code)
+	}
+}
+
+func TestBenchmarkHandler(t *testing.T) {
+	handler := newHTTPHandler(NewMockOrderService(), nil, feed.NewHub(1), newTestAuthenticator(t), PIIPolicy{}, config.HTTPConfig{})
+	serve := func(target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// бенчмарк нагружает БД, поэтому доступен только администратору
+	if w := serve("/benchmark/found123", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидался статус 401 без токена, получен %d", w.Code)
+	}
+	if w := serve("/benchmark/found123", "reader-key"); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидался статус 403 для роли reader, получен %d", w.Code)
+	}
+
+	w := serve("/benchmark/found123?iterations=500&concurrency=8", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body.String())
+	}
+	var result service.BenchmarkResult
+	json.NewDecoder(w.Body).Decode(&result)
+	if result.Iterations != 500 || result.Concurrency != 8 || result.DB.P99Ms != 5 {
+		t.Errorf("Неверный результат бенчмарка: %+v", result)
+	}
+
+	tests := []struct {
+		target string
+		status int
+	}{
+		{"/benchmark/found123?iterations=abc", http.StatusBadRequest},
+		{"/benchmark/found123?concurrency=1000", http.StatusBadRequest},
+		{"/benchmark/missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := serve(tt.target, "secret"); w.Code != tt.status {
+			t.Errorf("%s: ожидался статус %d, получен %d", tt.target, tt.status, w.Code)
+		}
+	}
+}
+
+func TestOrderConditionalGet(t *testing.T) {
+	orderService := NewMockOrderService()
+	updatedAt := time.Date(2024, 3, 1, 12, 30, 15, 500, time.UTC)
+	order := orderService.orders["found123"]
+	order.UpdatedAt = updatedAt
+	orderService.orders["found123"] = order
+	handler := newHTTPHandler(orderService, nil, feed.NewHub(1), newTestAuthenticator(t), PIIPolicy{}, config.HTTPConfig{})
+
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/orders/found123", nil)
+		req.Header.Set("X-API-Key", "reader-key")
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("Ожидался статус 200 с сильным ETag, получено %d %q", w.Code, etag)
+	}
+	if w.Header().Get("Last-Modified") != "Fri, 01 Mar 2024 12:30:15 GMT" {
+		t.Errorf("Неверный Last-Modified: %q", w.Header().Get("Last-Modified"))
+	}
+
+	// тот же заказ дает тот же ETag
+	if again := get(nil).Header().Get("ETag"); again != etag {
+		t.Errorf("ETag изменился без изменения заказа: %s -> %s", etag, again)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{"совпадающий ETag", http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+		{"ETag в списке", http.Header{"If-None-Match": {`"other", ` + etag}}, http.StatusNotModified},
+		{"другой ETag", http.Header{"If-None-Match": {`"other"`}}, http.StatusOK},
+		{"не изменялся с", http.Header{"If-Modified-Since": {"Fri, 01 Mar 2024 12:30:15 GMT"}}, http.StatusNotModified},
+		{"изменялся после", http.Header{"If-Modified-Since": {"Fri, 01 Mar 2024 12:30:14 GMT"}}, http.StatusOK},
+		// If-None-Match приоритетнее даты
+		{"ETag важнее даты", http.Header{
+			"If-None-Match":     {`"other"`},
+			"If-Modified-Since": {"Fri, 01 Mar 2024 12:30:15 GMT"},
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := get(tt.header)
+		if w.Code != tt.status {
+			t.Errorf("%s: ожидался статус %d, получен %d", tt.name, tt.status, w.Code)
+		}
+		if tt.status == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("%s: ответ 304 не должен содержать тело", tt.name)
+		}
+	}
+}
+
+func TestOrderPIIMasking(t *testing.T) {
+	orderService := NewMockOrderService()
+	policy := PIIPolicy{Masker: pii.NewMasker(pii.DefaultRules()), UnmaskedRole: auth.RoleSupport}
+	handler := newHTTPHandler(orderService, nil, feed.NewHub(1), newTestAuthenticator(t), policy, config.HTTPConfig{})
+
+	get := func(target, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// reader получает заказ с маской, ответ различается по ключу
+	w := get("/api/v1/orders/found123", "reader-key")
+	var order database.Order
+	json.NewDecoder(w.Body).Decode(&order)
+	if order.Delivery.Name != "T*** U***" || order.Delivery.Phone != "+******7890" || order.Payment.Transaction != "***" {
+		t.Errorf("Ожидались замаскированные данные: %+v %+v", order.Delivery, order.Payment)
+	}
+	if vary := strings.Join(w.Header().Values("Vary"), ", "); !strings.Contains(vary, "X-API-Key") {
+		t.Errorf("Нет X-API-Key в Vary: %q", vary)
+	}
+
+	var list struct {
+		Orders []database.Order `json:"orders"`
+	}
+	json.NewDecoder(get("/api/v1/tracks/FOUND_TRACK/orders", "reader-key").Body).Decode(&list)
+	if len(list.Orders) != 1 || list.Orders[0].Delivery.Name != "T*** U***" {
+		t.Errorf("Список заказов не замаскирован: %+v", list.Orders)
+	}
+
+	// support и выше видят данные без маски, данные сервиса не изменены
+	for _, key := range []string{"support-key", "secret"} {
+		order = database.Order{}
+		json.NewDecoder(get("/api/v1/orders/found123", key).Body).Decode(&order)
+		if order.Delivery.Name != "Test User" || order.Payment.Transaction != "test_txn" {
+			t.Errorf("%s: ожидались данные без маски: %+v", key, order.Delivery)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat(`{"order_uid":"compress"}`, 200)
+	handler := withCompression(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/small" {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+			return
+		}
+		writeCacheableJSON(w, r, large, time.Time{})
+	}))
+
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) string {
+		var body string
+		if err := json.NewDecoder(decodeBody(t, w)).Decode(&body); err != nil {
+			t.Fatalf("Ошибка декодирования тела (%s): %v", w.Header().Get("Content-Encoding"), err)
+		}
+		return body
+	}
+
+	plain := serve("/large", nil)
+	if plain.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Без Accept-Encoding ответ не должен сжиматься")
+	}
+	plainETag := plain.Header().Get("ETag")
+
+	for _, tt := range []struct{ accept, encoding string }{
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip;q=0.8", "gzip"},
+		{"*", "br"},
+		{"br;q=0, *", "gzip"},
+		{"identity", ""},
+	} {
+		w := serve("/large", http.Header{"Accept-Encoding": {tt.accept}})
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%q: ожидалась кодировка %q, получена %q", tt.accept, tt.encoding, got)
+			continue
+		}
+		if body := decode(w); body != large {
+			t.Errorf("%q: тело после распаковки не совпадает", tt.accept)
+		}
+		if tt.encoding == "" {
+			continue
+		}
+		if !strings.Contains(w.Header().Get("Vary"), "Accept-Encoding") {
+			t.Errorf("%q: нет Vary: Accept-Encoding", tt.accept)
+		}
+
+		// сжатое представление имеет свой ETag, и по нему тоже возвращается 304
+		etag := w.Header().Get("ETag")
+		if etag == plainETag || !strings.HasSuffix(etag, "-"+tt.encoding+`"`) {
+			t.Errorf("%q: ожидался ETag с суффиксом кодировки, получен %s", tt.accept, etag)
+		}
+		cached := serve("/large", http.Header{"Accept-Encoding": {tt.accept}, "If-None-Match": {etag}})
+		if cached.Code != http.StatusNotModified || cached.Header().Get("ETag") != etag {
+			t.Errorf("%q: ожидался 304 с ETag %s, получен %d %s", tt.accept, etag, cached.Code, cached.Header().Get("ETag"))
+		}
+	}
+
+	// короткие ответы не сжимаются
+	small := serve("/small", http.Header{"Accept-Encoding": {"gzip"}})
+	if small.Header().Get("Content-Encoding") != "" || !strings.Contains(small.Body.String(), `"ok"`) {
+		t.Errorf("Короткий ответ не должен сжиматься: %q", small.Header().Get("Content-Encoding"))
+	}
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) io.Reader {
+	switch w.Header().Get("Content-Encoding") {
+	case "gzip":
+		reader, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("Ошибка чтения gzip: %v", err)
+		}
+		return reader
+	case "br":
+		return brotli.NewReader(w.Body)
+	default:
+		return w.Body
+	}
+}
@@ -6,27 +6,26 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"order-service/internal/auth"
+	"order-service/internal/config"
+	"order-service/internal/feed"
 	"order-service/internal/service"
+	"order-service/internal/webhook"
 	"time"
 )
 
-func StartHTTPServer(ctx context.Context, orderService service.OrderService, port string) {
+// максимальное количество заказов покупателя в ответе
+const maxCustomerOrders = 500
+
+// webhooks может быть nil, тогда управление вебхуками недоступно
+func StartHTTPServer(ctx context.Context, orderService service.OrderService, webhooks webhook.Manager, orderFeed feed.Hub, authn auth.Authenticator, piiPolicy PIIPolicy, cfg config.HTTPConfig) {
+	port := cfg.Port
 	server := &http.Server{
 		Addr:    port,
-		Handler: nil,
+		Handler: newHTTPHandler(orderService, webhooks, orderFeed, authn, piiPolicy, cfg),
 	}
-
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("../../static"))))
-	http.HandleFunc("/order/", enableCORS(orderHandler(orderService)))
-	http.HandleFunc("/cache", enableCORS(cacheHandler(orderService)))
-	http.HandleFunc("/health", enableCORS(healthHandler(orderService)))
-	http.HandleFunc("/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/" {
-			http.ServeFile(w, r, "../../static/index.html")
-			return
-		}
-		http.NotFound(w, r)
-	}))
+	// Shutdown не ждет потоковые соединения, закрываем их подписки сами
+	server.RegisterOnShutdown(orderFeed.Close)
 
 	go func() {
 		<-ctx.Done()
@@ -42,61 +41,99 @@ func StartHTTPServer(ctx context.Context, orderService service.OrderService, por
 
 	log.Printf("   HTTP сервер запущен на %s", port)
 	log.Printf("   http://localhost%s/ - веб-интерфейс", port)
-	log.Printf("   http://localhost%s/order/{id} - получить заказ", port)
+	log.Printf("   http://localhost%s/api/v1/orders/{id} - получить заказ (прежний путь /order/{id})", port)
+	log.Printf("   http://localhost%s/api/v1/tracks/{track}/orders - заказы по трек-номеру", port)
+	log.Printf("   http://localhost%s/api/v1/customers/{id}/orders - заказы покупателя", port)
+	log.Printf("   http://localhost%s/api/v1/orders - поиск (GET) и прием (POST) заказов", port)
+	log.Printf("   http://localhost%s/api/v1/orders:validate - проверка заказа без сохранения", port)
+	log.Printf("   http://localhost%s/api/v1/orders/search?q= - поиск по товарам", port)
+	log.Printf("   http://localhost%s/api/v1/orders/export - выгрузка NDJSON/CSV", port)
+	log.Printf("   http://localhost%s/api/v1/orders/stream - живая лента заказов (SSE)", port)
+	log.Printf("   ws://localhost%s/api/v1/orders/ws - живая лента заказов (WebSocket)", port)
+	log.Printf("   http://localhost%s/api/v1/analytics/... - аналитика продаж", port)
+	log.Printf("   http://localhost%s/api/docs - документация API (спецификация /api/openapi.json)", port)
 	log.Printf("   http://localhost%s/cache - просмотр кэша", port)
 	log.Printf("   http://localhost%s/health - проверка здоровья", port)
-	log.Printf("   http://localhost%s/benchmark/{id} - тест производительности", port)
+	log.Printf("   http://localhost%s/ready - готовность и прогрев кэша", port)
+	log.Printf("   http://localhost%s/benchmark/{id}?iterations=&concurrency= - задержки кэша и БД (admin)", port)
+	log.Printf("   http://localhost%s/admin/cache/... - администрирование кэша (admin)", port)
+	if webhooks != nil {
+		log.Printf("   http://localhost%s/admin/webhooks - подписки на вебхуки", port)
+	}
 
 	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Ошибка HTTP сервера: %v", err)
 	}
 }
 
-func orderHandler(orderService service.OrderService) http.HandlerFunc {
+func orderHandler(orderService service.OrderService, piiPolicy PIIPolicy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-
-		orderUID := r.URL.Path[len("/order/"):]
-		if orderUID == "" {
-			http.Error(w, "Order ID is required", http.StatusBadRequest)
-			return
-		}
+		orderUID := r.PathValue("id")
 
 		fmt.Printf("Поиск заказа: %s\n", orderUID)
 
 		order, err := orderService.GetOrder(orderUID)
 		if err != nil {
-			fmt.Printf("Заказ не найден: %s\n", orderUID)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error":     "Order not found",
-				"order_uid": orderUID,
-				"message":   "Заказ с указанным ID не существует",
-			})
+			fmt.Printf("Заказ не получен: %s (%v)\n", orderUID, err)
+			writeError(w, r, err, map[string]interface{}{"order_uid": orderUID})
 			return
 		}
 
 		fmt.Printf("Найден заказ: %s\n", orderUID)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(order)
+		writeCacheableJSON(w, r, piiPolicy.order(w, r, order), order.UpdatedAt)
 	}
 }
 
-func cacheHandler(orderService service.OrderService) http.HandlerFunc {
+func ordersByTrackHandler(orderService service.OrderService, piiPolicy PIIPolicy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		trackNumber := r.PathValue("track")
+
+		orders, err := orderService.GetOrdersByTrackNumber(trackNumber)
+		if err != nil {
+			writeError(w, r, err, map[string]interface{}{"track_number": trackNumber})
+			return
+		}
+
+		// список может измениться без изменения updated_at отдельных заказов, поэтому только ETag
+		writeCacheableJSON(w, r, map[string]interface{}{
+			"track_number": trackNumber,
+			"count":        len(orders),
+			"orders":       piiPolicy.orders(w, r, orders),
+		}, time.Time{})
+	}
+}
+
+func ordersByCustomerHandler(orderService service.OrderService, piiPolicy PIIPolicy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID := r.PathValue("id")
+
+		limit, err := queryInt(r, "limit", 50)
+		if err != nil || limit <= 0 || limit > maxCustomerOrders {
+			writeBadRequest(w, r, fmt.Sprintf("limit: ожидается число от 1 до %d", maxCustomerOrders))
+			return
+		}
+
+		orders, err := orderService.GetOrdersByCustomerID(customerID, limit)
+		if err != nil {
+			writeError(w, r, err, map[string]interface{}{"customer_id": customerID})
 			return
 		}
 
+		writeCacheableJSON(w, r, map[string]interface{}{
+			"customer_id": customerID,
+			"count":       len(orders),
+			"orders":      piiPolicy.orders(w, r, orders),
+		}, time.Time{})
+	}
+}
+
+func cacheHandler(orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		cacheInfo := map[string]interface{}{
 			"cache_size":  orderService.GetCacheSize(),
+			"stats":       orderService.GetCacheStats(),
 			"server_time": time.Now().Format(time.RFC3339),
 			"message":     "Информация о кэше доступна через сервисный слой",
 		}
@@ -107,11 +144,6 @@ func cacheHandler(orderService service.OrderService) http.HandlerFunc {
 
 func healthHandler(orderService service.OrderService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-
 		w.Header().Set("Content-Type", "application/json")
 
 		dbStatus := "healthy"
@@ -134,13 +166,46 @@ func healthHandler(orderService service.OrderService) http.HandlerFunc {
 	}
 }
 
-func enableCORS(next http.HandlerFunc) http.HandlerFunc {
+func readyHandler(orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		progress := orderService.GetWarmupProgress()
+		dbStatus := "healthy"
+		if err := orderService.CheckDBConnection(); err != nil {
+			dbStatus = "unhealthy"
+		}
+
+		ready := progress.Done() && dbStatus == "healthy"
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ready":        ready,
+			"db_status":    dbStatus,
+			"cache_size":   orderService.GetCacheSize(),
+			"cache_warmup": progress,
+			"timestamp":    time.Now().Format(time.RFC3339),
+		})
+	}
+}
+
+// отвечает на кросс-доменные запросы только разрешенным источникам; учетные данные
+// передаются заголовками, cookies не используются
+func enableCORS(policy corsPolicy, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if policy.allowed(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, X-API-Key, Content-Type, If-None-Match, If-Modified-Since")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified, X-Request-ID, WWW-Authenticate")
+		}
 
-		if r.Method == "OPTIONS" {
+		// preflight не содержит учетных данных и не доходит до маршрутов
+		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
@@ -0,0 +1,388 @@
+package handler
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"order-service/internal/auth"
+	"order-service/internal/database"
+	"order-service/internal/feed"
+	"order-service/internal/openapi"
+	"order-service/internal/service"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// описание маршрута для спецификации; ключ в apiDocs - "METHOD pattern"
+type apiDoc struct {
+	Summary  string
+	Tag      string
+	Query    []openapi.Parameter
+	Body     *openapi.Schema
+	Status   int
+	Response map[string]openapi.MediaType
+	// пустая роль - маршрут открыт без аутентификации
+	Role       auth.Role
+	Deprecated bool
+}
+
+// GET /api/openapi.json - спецификация строится по маршрутам при первом запросе
+func openAPIHandler(router *Router) http.HandlerFunc {
+	spec := sync.OnceValues(func() ([]byte, error) {
+		return json.Marshal(buildOpenAPI(router.Routes()))
+	})
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := spec()
+		if err != nil {
+			writeError(w, r, err, nil)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+// GET /api/docs - страница документации, читает /api/openapi.json
+func docsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
+
+// собирает документ OpenAPI по зарегистрированным маршрутам;
+// маршрут без описания в apiDocs попадает в документ без summary, это ловит тест
+func buildOpenAPI(routes []RouteInfo) *openapi.Document {
+	gen := openapi.NewGenerator()
+	docs := apiDocs(gen)
+	errorRef := gen.Define("Error", &openapi.Schema{
+		Type:     "object",
+		Required: []string{"code", "message"},
+		Properties: map[string]*openapi.Schema{
+			"code":       {Type: "string", Description: "машиночитаемый код ошибки, например order_not_found"},
+			"message":    {Type: "string"},
+			"details":    {Type: "object", Description: "для validation_failed содержит errors - список FieldError"},
+			"request_id": {Type: "string", Description: "совпадает с заголовком X-Request-ID"},
+		},
+	})
+	gen.Schema(service.FieldError{})
+
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "Order Service API",
+			Version:     "1.0.0",
+			Description: "Поиск, прием и аналитика заказов. Ошибки возвращаются в формате Error.",
+		},
+		Paths: make(map[string]openapi.PathItem),
+	}
+
+	for _, route := range routes {
+		info := docs[route.Method+" "+route.Pattern]
+		status := info.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		op := &openapi.Operation{
+			Summary:     info.Summary,
+			OperationID: operationID(route),
+			Parameters:  append(pathParameters(route.Pattern), info.Query...),
+			Responses: map[string]openapi.Response{
+				strconv.Itoa(status): {Description: http.StatusText(status), Content: info.Response},
+				"default":            {Description: "Ошибка", Content: openapi.JSONContent(errorRef)},
+			},
+			Deprecated: info.Deprecated,
+		}
+		if info.Tag != "" {
+			op.Tags = []string{info.Tag}
+		}
+		if info.Body != nil {
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSONContent(info.Body)}
+		}
+		if info.Role != "" {
+			op.Description = "Требуется роль " + string(info.Role) + " или выше."
+			op.Security = []map[string][]string{{"apiKey": {}}, {"bearerAuth": {}}}
+			op.Responses["401"] = openapi.Response{Description: "Нет учетных данных или они неверны", Content: openapi.JSONContent(errorRef)}
+			op.Responses["403"] = openapi.Response{Description: "Недостаточно прав", Content: openapi.JSONContent(errorRef)}
+		}
+
+		item := doc.Paths[route.Pattern]
+		if item == nil {
+			item = make(openapi.PathItem)
+			doc.Paths[route.Pattern] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	doc.Components = openapi.Components{
+		Schemas: gen.Components(),
+		SecuritySchemes: map[string]openapi.SecurityScheme{
+			"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "API ключ; его же можно передать как Bearer"},
+			"bearerAuth": {
+				Type: "http", Scheme: "bearer", BearerFormat: "JWT",
+				Description: "JWT с ролью в claim role или API ключ; потоковые маршруты принимают параметр access_token",
+			},
+		},
+	}
+	return doc
+}
+
+// параметры пути вида {id} всегда обязательные строки
+func pathParameters(pattern string) []openapi.Parameter {
+	var params []openapi.Parameter
+	for _, segment := range strings.Split(pattern, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, openapi.Parameter{
+				Name:     segment[1 : len(segment)-1],
+				In:       "path",
+				Required: true,
+				Schema:   &openapi.Schema{Type: "string"},
+			})
+		}
+	}
+	return params
+}
+
+// operationId из метода и пути: GET /api/v1/orders/{id} -> getApiV1OrdersId
+func operationID(route RouteInfo) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+	upper := true
+	for _, r := range route.Pattern {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if route.Pattern == "/" {
+		b.WriteString("Root")
+	}
+	return b.String()
+}
+
+func queryParam(name, typ, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: typ}}
+}
+
+// JSON объект со списком: {"count": N, "<field>": [...]}
+func listSchema(field string, item *openapi.Schema) map[string]openapi.MediaType {
+	return openapi.JSONContent(&openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"count": {Type: "integer"},
+			field:   {Type: "array", Items: item},
+		},
+	})
+}
+
+func objectSchema(description string) map[string]openapi.MediaType {
+	return openapi.JSONContent(&openapi.Schema{Type: "object", Description: description})
+}
+
+// описания всех маршрутов сервиса
+func apiDocs(gen *openapi.Generator) map[string]apiDoc {
+	order := gen.Schema(database.Order{})
+	orders := listSchema("orders", order)
+
+	limit := func(def, max int) openapi.Parameter {
+		return queryParam("limit", "integer", "по умолчанию "+strconv.Itoa(def)+", не больше "+strconv.Itoa(max))
+	}
+	searchFilters := []openapi.Parameter{
+		queryParam("customer_id", "string", ""),
+		queryParam("track_number", "string", ""),
+		queryParam("delivery_service", "string", ""),
+		queryParam("provider", "string", "платежный провайдер"),
+		queryParam("currency", "string", ""),
+		queryParam("brand", "string", ""),
+		queryParam("nm_id", "integer", ""),
+		queryParam("min_amount", "integer", ""),
+		queryParam("max_amount", "integer", ""),
+		queryParam("date_from", "string", "YYYY-MM-DD или RFC3339"),
+		queryParam("date_to", "string", "YYYY-MM-DD или RFC3339, дата включается целиком"),
+		queryParam("sort", "string", "date_created или amount, минус - по убыванию; по умолчанию -date_created"),
+	}
+	period := []openapi.Parameter{
+		queryParam("date_from", "string", "YYYY-MM-DD или RFC3339"),
+		queryParam("date_to", "string", "YYYY-MM-DD или RFC3339, дата включается целиком"),
+	}
+	salesFilters := append([]openapi.Parameter{
+		{Name: "interval", In: "query", Schema: &openapi.Schema{
+			Type: "string", Enum: []string{database.IntervalDay, database.IntervalWeek, database.IntervalMonth},
+		}},
+		{Name: "group_by", In: "query", Schema: &openapi.Schema{
+			Type: "string", Enum: []string{"currency", "provider", "bank", "delivery_service", "region"},
+		}},
+	}, period...)
+	topFilters := append(salesFilters[:len(salesFilters):len(salesFilters)], limit(10, maxTopSize))
+	feedFilters := []openapi.Parameter{
+		queryParam("entry", "string", ""),
+		queryParam("delivery_service", "string", ""),
+	}
+
+	orderDoc := apiDoc{Summary: "Заказ по order_uid", Tag: "orders", Role: auth.RoleReader, Response: openapi.JSONContent(order)}
+	trackDoc := apiDoc{Summary: "Заказы по трек-номеру", Tag: "orders", Role: auth.RoleReader, Response: orders}
+	customerDoc := apiDoc{Summary: "Заказы покупателя, сначала новые", Tag: "orders", Role: auth.RoleReader, Response: orders,
+		Query: []openapi.Parameter{limit(50, maxCustomerOrders)}}
+	legacy := func(doc apiDoc) apiDoc {
+		doc.Summary += " (прежний путь)"
+		doc.Deprecated = true
+		return doc
+	}
+
+	webhookSubscription := gen.Schema(database.WebhookSubscription{})
+
+	return map[string]apiDoc{
+		"GET /api/v1/orders": {
+			Summary: "Поиск заказов по фильтрам с пагинацией курсором", Tag: "orders", Role: auth.RoleReader,
+			Query: append(searchFilters, limit(50, maxSearchPageSize), queryParam("cursor", "string", "next_cursor предыдущей страницы")),
+			Response: openapi.JSONContent(&openapi.Schema{
+				Type: "object",
+				Properties: map[string]*openapi.Schema{
+					"count":       {Type: "integer"},
+					"orders":      {Type: "array", Items: order},
+					"next_cursor": {Type: "string"},
+				},
+			}),
+		},
+		"POST /api/v1/orders": {
+			Summary: "Прием заказа", Tag: "orders", Role: auth.RoleSupport, Body: order, Status: http.StatusCreated,
+			Response: objectSchema("order_uid и status: created; заголовок Location указывает на заказ"),
+		},
+		"POST /api/v1/orders:validate": {
+			Summary: "Проверка заказа без сохранения", Tag: "orders", Role: auth.RoleSupport, Body: order,
+			Response: objectSchema("valid: true и order_uid"),
+		},
+		"GET /api/v1/orders/search": {
+			Summary: "Полнотекстовый поиск по товарам", Tag: "orders", Role: auth.RoleReader,
+			Query: []openapi.Parameter{
+				{Name: "q", In: "query", Required: true, Schema: &openapi.Schema{Type: "string"}},
+				limit(20, maxSearchPageSize),
+				queryParam("offset", "integer", ""),
+			},
+			Response: listSchema("results", gen.Schema(database.OrderTextMatch{})),
+		},
+		"GET /api/v1/orders/export": {
+			Summary: "Выгрузка заказов в NDJSON или CSV", Tag: "orders", Role: auth.RoleReader,
+			Query: append(searchFilters,
+				queryParam("limit", "integer", "по умолчанию без ограничения"),
+				queryParam("format", "string", "ndjson или csv"),
+				queryParam("gzip", "boolean", "")),
+			Response: map[string]openapi.MediaType{
+				"application/x-ndjson": {Schema: order},
+				"text/csv":             {Schema: &openapi.Schema{Type: "string"}},
+				"application/gzip":     {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+			},
+		},
+		"GET /api/v1/orders/stream": {
+			Summary: "Живая лента новых заказов (Server-Sent Events)", Tag: "feed", Role: auth.RoleReader, Query: feedFilters,
+			Response: map[string]openapi.MediaType{
+				"text/event-stream": {Schema: gen.Schema(feed.Event{})},
+			},
+		},
+		"GET /api/v1/orders/ws": {
+			Summary: "Живая лента новых заказов (WebSocket)", Tag: "feed", Role: auth.RoleReader, Query: feedFilters,
+			Status: http.StatusSwitchingProtocols,
+		},
+		"GET /api/v1/orders/{id}":           orderDoc,
+		"GET /api/v1/tracks/{track}/orders": trackDoc,
+		"GET /api/v1/customers/{id}/orders": customerDoc,
+		"GET /order/{id}":                   legacy(orderDoc),
+		"GET /orders/by-track/{track}":      legacy(trackDoc),
+		"GET /orders/by-customer/{id}":      legacy(customerDoc),
+		"GET /api/v1/analytics/sales": {
+			Summary: "Выручка и количество заказов по периодам", Tag: "analytics", Role: auth.RoleReader, Query: salesFilters,
+			Response: openapi.JSONContent(&openapi.Schema{
+				Type: "object",
+				Properties: map[string]*openapi.Schema{
+					"interval": {Type: "string"},
+					"group_by": {Type: "string"},
+					"points":   {Type: "array", Items: gen.Schema(database.SalesPoint{})},
+				},
+			}),
+		},
+		"GET /api/v1/analytics/top-brands": {
+			Summary: "Топ брендов по выручке в каждом периоде и значении измерения", Tag: "analytics", Role: auth.RoleReader,
+			Query: topFilters, Response: listSchema("top", gen.Schema(database.TopEntry{})),
+		},
+		"GET /api/v1/analytics/top-products": {
+			Summary: "Топ товаров по выручке в каждом периоде и значении измерения", Tag: "analytics", Role: auth.RoleReader,
+			Query: topFilters, Response: listSchema("top", gen.Schema(database.TopEntry{})),
+		},
+
+		"GET /api/openapi.json": {Summary: "Эта спецификация", Tag: "service", Response: objectSchema("документ OpenAPI 3")},
+		"GET /api/docs":         {Summary: "Документация API", Tag: "service", Response: htmlContent()},
+		"GET /cache":            {Summary: "Размер и статистика кэша", Tag: "service", Role: auth.RoleReader, Response: objectSchema("")},
+		"GET /health":           {Summary: "Проверка здоровья", Tag: "service", Response: objectSchema("")},
+		"GET /ready":            {Summary: "Готовность и прогрев кэша, 503 пока сервис не готов", Tag: "service", Response: objectSchema("")},
+		"GET /":                 {Summary: "Веб-интерфейс", Tag: "service", Response: htmlContent()},
+
+		"GET /admin/cache/keys": {
+			Summary: "Ключи локального кэша (без Redis), сначала новые", Tag: "admin", Role: auth.RoleAdmin,
+			Query:    []openapi.Parameter{queryParam("offset", "integer", ""), limit(100, maxCacheKeysPageSize)},
+			Response: listSchema("keys", gen.Schema(service.CacheEntryInfo{})),
+		},
+		"DELETE /admin/cache/keys/{id}": {Summary: "Удалить заказ из кэша", Tag: "admin", Role: auth.RoleAdmin, Response: objectSchema("")},
+		"POST /admin/cache/flush":       {Summary: "Очистить кэш, включая Redis и кэш отсутствующих заказов", Tag: "admin", Role: auth.RoleAdmin, Response: objectSchema("flushed - количество удаленных записей")},
+		"POST /admin/cache/warm": {
+			Summary: "Загрузить заказы в кэш", Tag: "admin", Role: auth.RoleAdmin,
+			Body: &openapi.Schema{
+				Type:       "object",
+				Required:   []string{"order_ids"},
+				Properties: map[string]*openapi.Schema{"order_ids": {Type: "array", Items: &openapi.Schema{Type: "string"}}},
+			},
+			Response: openapi.JSONContent(gen.Schema(service.CacheWarmResult{})),
+		},
+
+		"GET /benchmark/{id}": {
+			Summary: "Задержки чтения заказа из кэша и из БД: p50/p95/p99/max и пропускная способность", Tag: "admin", Role: auth.RoleAdmin,
+			Query: []openapi.Parameter{
+				queryParam("iterations", "integer", fmt.Sprintf("по умолчанию %d, не больше %d", service.DefaultBenchmarkIterations, service.MaxBenchmarkIterations)),
+				queryParam("concurrency", "integer", fmt.Sprintf("по умолчанию 1, не больше %d", service.MaxBenchmarkConcurrency)),
+			},
+			Response: openapi.JSONContent(gen.Schema(service.BenchmarkResult{})),
+		},
+
+		"GET /admin/webhooks": {
+			Summary: "Подписки на вебхуки", Tag: "webhooks", Role: auth.RoleSupport,
+			Response: listSchema("subscriptions", webhookSubscription),
+		},
+		"POST /admin/webhooks": {
+			Summary: "Создать подписку; секрет возвращается только в этом ответе", Tag: "webhooks", Role: auth.RoleAdmin,
+			Body: &openapi.Schema{
+				Type:     "object",
+				Required: []string{"url"},
+				Properties: map[string]*openapi.Schema{
+					"url":              {Type: "string", Format: "uri"},
+					"secret":           {Type: "string", Description: "если не задан, генерируется"},
+					"delivery_service": {Type: "string"},
+					"entry":            {Type: "string"},
+				},
+			},
+			Status: http.StatusCreated, Response: openapi.JSONContent(webhookSubscription),
+		},
+		"DELETE /admin/webhooks/{id}": {Summary: "Удалить подписку", Tag: "webhooks", Role: auth.RoleAdmin, Response: objectSchema("")},
+		"GET /admin/webhooks/{id}/deliveries": {
+			Summary: "Журнал доставки подписки", Tag: "webhooks", Role: auth.RoleSupport,
+			Query:    []openapi.Parameter{limit(50, maxWebhookDeliveries)},
+			Response: listSchema("deliveries", gen.Schema(database.WebhookDelivery{})),
+		},
+		"POST /admin/webhooks/deliveries/{id}/redeliver": {
+			Summary: "Отправить доставку повторно", Tag: "webhooks", Role: auth.RoleAdmin,
+			Status: http.StatusAccepted, Response: objectSchema(""),
+		},
+	}
+}
+
+func htmlContent() map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"text/html": {Schema: &openapi.Schema{Type: "string"}}}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+	"order-service/internal/auth"
+	"order-service/internal/database"
+	"order-service/internal/pii"
+)
+
+// маскирование персональных данных покупателя в ответах с заказами
+type PIIPolicy struct {
+	// nil - данные отдаются без маски
+	Masker pii.Masker
+	// клиенты с этой ролью и выше видят данные без маски; пустая роль - маска для всех
+	UnmaskedRole auth.Role
+}
+
+// нужно ли маскировать ответ на запрос; ответ зависит от учетных данных,
+// поэтому кэши должны различать их
+func (p PIIPolicy) masked(w http.ResponseWriter, r *http.Request) bool {
+	if p.Masker == nil {
+		return false
+	}
+	w.Header().Add("Vary", "Authorization, X-API-Key")
+	principal, ok := auth.PrincipalFrom(r.Context())
+	return !ok || p.UnmaskedRole == "" || !principal.Role.Allows(p.UnmaskedRole)
+}
+
+func (p PIIPolicy) order(w http.ResponseWriter, r *http.Request, order database.Order) database.Order {
+	if !p.masked(w, r) {
+		return order
+	}
+	return p.Masker.MaskOrder(order)
+}
+
+// возвращает копию списка, заказы в кэше сервиса не меняются
+func (p PIIPolicy) orders(w http.ResponseWriter, r *http.Request, orders []database.Order) []database.Order {
+	if !p.masked(w, r) {
+		return orders
+	}
+	masked := make([]database.Order, len(orders))
+	for i, order := range orders {
+		masked[i] = p.Masker.MaskOrder(order)
+	}
+	return masked
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// заголовок с идентификатором запроса, возвращается в ответе и в теле ошибок
+const requestIDHeader = "X-Request-ID"
+
+// допустимый идентификатор запроса от клиента, иначе генерируем свой
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// маршрутизатор с выбором по методу и параметрами пути вида /orders/{id};
+// параметры доступны через r.PathValue, ошибки маршрутизации пишутся в формате API
+type Router struct {
+	routes []route
+}
+
+type route struct {
+	method   string
+	pattern  string
+	segments []string
+	handler  http.HandlerFunc
+}
+
+// зарегистрированный маршрут, по списку маршрутов строится спецификация OpenAPI
+type RouteInfo struct {
+	Method  string
+	Pattern string
+}
+
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// регистрирует обработчик; сегмент {name} совпадает с любым непустым сегментом пути
+func (rt *Router) Handle(method, pattern string, handler http.HandlerFunc) {
+	rt.routes = append(rt.routes, route{
+		method:   method,
+		pattern:  pattern,
+		segments: strings.Split(strings.Trim(pattern, "/"), "/"),
+		handler:  handler,
+	})
+}
+
+// маршруты в порядке регистрации
+func (rt *Router) Routes() []RouteInfo {
+	routes := make([]RouteInfo, len(rt.routes))
+	for i, route := range rt.routes {
+		routes[i] = RouteInfo{Method: route.method, Pattern: route.pattern}
+	}
+	return routes
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	var allowed []string
+	for _, route := range rt.routes {
+		params, ok := route.match(path)
+		if !ok {
+			continue
+		}
+		if route.method != r.Method && !(route.method == http.MethodGet && r.Method == http.MethodHead) {
+			if !slices.Contains(allowed, route.method) {
+				allowed = append(allowed, route.method)
+			}
+			continue
+		}
+		for name, value := range params {
+			r.SetPathValue(name, value)
+		}
+		route.handler(w, r)
+		return
+	}
+
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeAPIError(w, r, http.StatusMethodNotAllowed, "method_not_allowed",
+			"Метод "+r.Method+" не поддерживается", map[string]interface{}{"allow": allowed})
+		return
+	}
+	writeAPIError(w, r, http.StatusNotFound, "not_found", "Ресурс не найден", nil)
+}
+
+func (rt route) match(path []string) (map[string]string, bool) {
+	if len(path) != len(rt.segments) {
+		return nil, false
+	}
+
+	var params map[string]string
+	for i, segment := range rt.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if path[i] == "" {
+				return nil, false
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[segment[1:len(segment)-1]] = path[i]
+			continue
+		}
+		if segment != path[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// присваивает запросу идентификатор: берет X-Request-ID клиента или генерирует новый
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			buf := make([]byte, 8)
+			rand.Read(buf)
+			id = hex.EncodeToString(buf)
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// идентификатор запроса, пустой вне withRequestID
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"order-service/internal/auth"
+	"order-service/internal/config"
+	"order-service/internal/feed"
+	"order-service/internal/service"
+	"order-service/internal/webhook"
+)
+
+// собирает все маршруты сервиса; webhooks может быть nil
+func newHTTPHandler(orderService service.OrderService, webhooks webhook.Manager, orderFeed feed.Hub, authn auth.Authenticator, piiPolicy PIIPolicy, cfg config.HTTPConfig) http.Handler {
+	router := newRouter(orderService, webhooks, orderFeed, authn, piiPolicy, cfg)
+
+	mux := http.NewServeMux()
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("../../static"))))
+	mux.Handle("/", enableCORS(corsPolicy{origins: cfg.CORSOrigins}, router.ServeHTTP))
+	return withRequestID(withCompression(mux))
+}
+
+// маршруты API; каждый маршрут должен быть описан в apiDocs для спецификации OpenAPI,
+// включая требуемую роль
+func newRouter(orderService service.OrderService, webhooks webhook.Manager, orderFeed feed.Hub, authn auth.Authenticator, piiPolicy PIIPolicy, cfg config.HTTPConfig) *Router {
+	router := NewRouter()
+	reader := func(h http.HandlerFunc) http.HandlerFunc { return requireRole(authn, auth.RoleReader, h) }
+	support := func(h http.HandlerFunc) http.HandlerFunc { return requireRole(authn, auth.RoleSupport, h) }
+	admin := func(h http.HandlerFunc) http.HandlerFunc { return requireRole(authn, auth.RoleAdmin, h) }
+
+	// REST API; литеральные пути регистрируются раньше /orders/{id}
+	router.Handle(http.MethodGet, "/api/v1/orders", reader(ordersSearchHandler(orderService, piiPolicy)))
+	router.Handle(http.MethodPost, "/api/v1/orders", support(orderSubmitHandler(orderService)))
+	router.Handle(http.MethodPost, "/api/v1/orders:validate", support(orderValidateHandler(orderService)))
+	router.Handle(http.MethodGet, "/api/v1/orders/search", reader(ordersTextSearchHandler(orderService, piiPolicy)))
+	router.Handle(http.MethodGet, "/api/v1/orders/export", reader(ordersExportHandler(orderService, piiPolicy)))
+	router.Handle(http.MethodGet, "/api/v1/orders/stream", withQueryToken(reader(ordersStreamHandler(orderFeed))))
+	router.Handle(http.MethodGet, "/api/v1/orders/ws", withQueryToken(reader(ordersWebSocketHandler(orderFeed, corsPolicy{origins: cfg.CORSOrigins}))))
+	router.Handle(http.MethodGet, "/api/v1/orders/{id}", reader(orderHandler(orderService, piiPolicy)))
+	router.Handle(http.MethodGet, "/api/v1/tracks/{track}/orders", reader(ordersByTrackHandler(orderService, piiPolicy)))
+	router.Handle(http.MethodGet, "/api/v1/customers/{id}/orders", reader(ordersByCustomerHandler(orderService, piiPolicy)))
+	router.Handle(http.MethodGet, "/api/v1/analytics/sales", reader(salesAnalyticsHandler(orderService)))
+	router.Handle(http.MethodGet, "/api/v1/analytics/top-brands", reader(topAnalyticsHandler(orderService, false)))
+	router.Handle(http.MethodGet, "/api/v1/analytics/top-products", reader(topAnalyticsHandler(orderService, true)))
+
+	// прежние пути, на них ссылаются существующие клиенты
+	router.Handle(http.MethodGet, "/order/{id}", reader(orderHandler(orderService, piiPolicy)))
+	router.Handle(http.MethodGet, "/orders/by-track/{track}", reader(ordersByTrackHandler(orderService, piiPolicy)))
+	router.Handle(http.MethodGet, "/orders/by-customer/{id}", reader(ordersByCustomerHandler(orderService, piiPolicy)))
+
+	// документация, проверки здоровья и веб-интерфейс открыты
+	router.Handle(http.MethodGet, "/api/openapi.json", openAPIHandler(router))
+	router.Handle(http.MethodGet, "/api/docs", docsHandler)
+
+	router.Handle(http.MethodGet, "/cache", reader(cacheHandler(orderService)))
+	router.Handle(http.MethodGet, "/health", healthHandler(orderService))
+	router.Handle(http.MethodGet, "/ready", readyHandler(orderService))
+
+	router.Handle(http.MethodGet, "/admin/cache/keys", admin(cacheKeysHandler(orderService)))
+	router.Handle(http.MethodDelete, "/admin/cache/keys/{id}", admin(cacheEvictHandler(orderService)))
+	router.Handle(http.MethodPost, "/admin/cache/flush", admin(cacheFlushHandler(orderService)))
+	router.Handle(http.MethodPost, "/admin/cache/warm", admin(cacheWarmHandler(orderService)))
+	router.Handle(http.MethodGet, "/benchmark/{id}", admin(benchmarkHandler(orderService)))
+	if webhooks != nil {
+		router.Handle(http.MethodGet, "/admin/webhooks", support(webhooksListHandler(webhooks)))
+		router.Handle(http.MethodPost, "/admin/webhooks", admin(webhookCreateHandler(webhooks)))
+		router.Handle(http.MethodDelete, "/admin/webhooks/{id}", admin(webhookDeleteHandler(webhooks)))
+		router.Handle(http.MethodGet, "/admin/webhooks/{id}/deliveries", support(webhookDeliveriesHandler(webhooks)))
+		router.Handle(http.MethodPost, "/admin/webhooks/deliveries/{id}/redeliver", admin(webhookRedeliverHandler(webhooks)))
+	}
+
+	router.Handle(http.MethodGet, "/", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "../../static/index.html")
+	})
+	return router
+}
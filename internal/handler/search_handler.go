@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"order-service/internal/database"
+	"order-service/internal/service"
+	"strings"
+	"time"
+)
+
+// максимальный размер страницы поиска
+const maxSearchPageSize = 500
+
+// GET /api/v1/orders?customer_id=...&sort=-date_created&limit=50&cursor=...
+func ordersSearchHandler(orderService service.OrderService, piiPolicy PIIPolicy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		filter, err := parseSearchFilter(r, 50, maxSearchPageSize)
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+
+		result, err := orderService.SearchOrders(r.Context(), filter)
+		if err != nil {
+			writeError(w, r, err, nil)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"count":       len(result.Orders),
+			"orders":      piiPolicy.orders(w, r, result.Orders),
+			"next_cursor": result.NextCursor,
+		})
+	}
+}
+
+// GET /api/v1/orders/search?q=Mascaras Vivienne Sabo&limit=20&offset=0
+func ordersTextSearchHandler(orderService service.OrderService, piiPolicy PIIPolicy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		text := strings.TrimSpace(r.URL.Query().Get("q"))
+		if text == "" {
+			writeBadRequest(w, r, "q: поисковый запрос обязателен")
+			return
+		}
+
+		limit, err := queryInt(r, "limit", 20)
+		if err != nil || limit <= 0 || limit > maxSearchPageSize {
+			writeBadRequest(w, r, fmt.Sprintf("limit: ожидается число от 1 до %d", maxSearchPageSize))
+			return
+		}
+		offset, err := queryInt(r, "offset", 0)
+		if err != nil || offset < 0 {
+			writeBadRequest(w, r, "offset: ожидается неотрицательное число")
+			return
+		}
+
+		matches, err := orderService.SearchOrdersText(r.Context(), text, limit, offset)
+		if err != nil {
+			writeError(w, r, err, map[string]interface{}{"query": text})
+			return
+		}
+		if piiPolicy.masked(w, r) {
+			for i := range matches {
+				matches[i].Order = piiPolicy.Masker.MaskOrder(matches[i].Order)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"query":   text,
+			"count":   len(matches),
+			"offset":  offset,
+			"results": matches,
+		})
+	}
+}
+
+// разбирает фильтры поиска; при maxLimit <= 0 limit не ограничен и 0 означает все заказы
+func parseSearchFilter(r *http.Request, defaultLimit, maxLimit int) (database.OrderSearchFilter, error) {
+	query := r.URL.Query()
+	filter := database.OrderSearchFilter{
+		CustomerID:      query.Get("customer_id"),
+		TrackNumber:     query.Get("track_number"),
+		DeliveryService: query.Get("delivery_service"),
+		PaymentProvider: query.Get("provider"),
+		Currency:        query.Get("currency"),
+		Brand:           query.Get("brand"),
+		Cursor:          query.Get("cursor"),
+	}
+
+	// по умолчанию сначала новые заказы
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = "-" + database.SortByDateCreated
+	}
+	filter.SortBy = strings.TrimPrefix(sort, "-")
+	filter.Desc = strings.HasPrefix(sort, "-")
+	if filter.SortBy != database.SortByDateCreated && filter.SortBy != database.SortByAmount {
+		return filter, fmt.Errorf("sort: допустимы date_created и amount")
+	}
+
+	var err error
+	if filter.Limit, err = queryInt(r, "limit", defaultLimit); err != nil || filter.Limit < 0 {
+		return filter, fmt.Errorf("limit: ожидается неотрицательное число")
+	}
+	if maxLimit > 0 && (filter.Limit == 0 || filter.Limit > maxLimit) {
+		return filter, fmt.Errorf("limit: ожидается число от 1 до %d", maxLimit)
+	}
+	if filter.NmID, err = queryInt(r, "nm_id", 0); err != nil || filter.NmID < 0 {
+		return filter, fmt.Errorf("nm_id: ожидается положительное число")
+	}
+	if filter.MinAmount, err = queryInt(r, "min_amount", 0); err != nil || filter.MinAmount < 0 {
+		return filter, fmt.Errorf("min_amount: ожидается неотрицательное число")
+	}
+	if filter.MaxAmount, err = queryInt(r, "max_amount", 0); err != nil || filter.MaxAmount < 0 {
+		return filter, fmt.Errorf("max_amount: ожидается неотрицательное число")
+	}
+	if filter.CreatedFrom, err = queryTime(r, "date_from", false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = queryTime(r, "date_to", true); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// разбирает границу периода из параметра запроса, см. database.ParseFilterTime
+func queryTime(r *http.Request, name string, upperBound bool) (time.Time, error) {
+	t, err := database.ParseFilterTime(r.URL.Query().Get(name), upperBound)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return t, nil
+}
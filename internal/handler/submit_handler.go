@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"order-service/internal/service"
+)
+
+// максимальный размер тела заказа
+const maxOrderBodySize = 1 << 20
+
+// POST /api/v1/orders - сохраняет заказ тем же путем, что и сообщения из Kafka,
+// с проверками orders:validate
+func orderSubmitHandler(orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := readOrderBody(w, r)
+		if !ok {
+			return
+		}
+
+		order, err := orderService.SubmitOrder(body)
+		if err != nil {
+			writeError(w, r, err, nil)
+			return
+		}
+
+		log.Printf("Заказ %s принят через HTTP", order.OrderUID)
+		w.Header().Set("Location", "/api/v1/orders/"+order.OrderUID)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"order_uid": order.OrderUID,
+			"status":    "created",
+		})
+	}
+}
+
+// POST /api/v1/orders:validate - проверка заказа без сохранения
+func orderValidateHandler(orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := readOrderBody(w, r)
+		if !ok {
+			return
+		}
+
+		order, err := orderService.CheckOrder(body)
+		if err != nil {
+			writeError(w, r, err, map[string]interface{}{"valid": false})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"valid":     true,
+			"order_uid": order.OrderUID,
+		})
+	}
+}
+
+func readOrderBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOrderBodySize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeAPIError(w, r, http.StatusRequestEntityTooLarge, "body_too_large", "Тело запроса превышает 1 МБ", nil)
+		return nil, false
+	}
+	if err != nil {
+		writeAPIError(w, r, http.StatusBadRequest, "invalid_body", "Не удалось прочитать тело запроса", nil)
+		return nil, false
+	}
+	return body, true
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"order-service/internal/database"
+	"order-service/internal/webhook"
+	"strconv"
+)
+
+// максимальное количество записей журнала доставки в ответе
+const maxWebhookDeliveries = 500
+
+// GET /admin/webhooks - список подписок
+func webhooksListHandler(webhooks webhook.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subs, err := webhooks.ListSubscriptions(r.Context())
+		if err != nil {
+			writeError(w, r, err, nil)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"count":         len(subs),
+			"subscriptions": subs,
+		})
+	}
+}
+
+// POST /admin/webhooks {"url": "...", "secret": "...", "delivery_service": "...", "entry": "..."}
+func webhookCreateHandler(webhooks webhook.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			URL             string `json:"url"`
+			Secret          string `json:"secret"`
+			DeliveryService string `json:"delivery_service"`
+			Entry           string `json:"entry"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, "invalid_json", "Тело запроса должно быть JSON объектом", nil)
+			return
+		}
+
+		sub, err := webhooks.CreateSubscription(r.Context(), database.WebhookSubscription{
+			URL:             request.URL,
+			Secret:          request.Secret,
+			DeliveryService: request.DeliveryService,
+			Entry:           request.Entry,
+		})
+		if err != nil {
+			writeError(w, r, err, nil)
+			return
+		}
+
+		log.Printf("Создана подписка на вебхуки %d: %s", sub.ID, sub.URL)
+		// секрет возвращается только при создании
+		writeJSON(w, http.StatusCreated, sub)
+	}
+}
+
+// DELETE /admin/webhooks/{id} - удалить подписку
+func webhookDeleteHandler(webhooks webhook.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := webhookID(w, r)
+		if !ok {
+			return
+		}
+		if err := webhooks.DeleteSubscription(r.Context(), id); err != nil {
+			writeError(w, r, err, map[string]interface{}{"id": id})
+			return
+		}
+		log.Printf("Подписка на вебхуки %d удалена администратором", id)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": true, "id": id})
+	}
+}
+
+// GET /admin/webhooks/{id}/deliveries?limit=50 - журнал доставки
+func webhookDeliveriesHandler(webhooks webhook.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := webhookID(w, r)
+		if !ok {
+			return
+		}
+		limit, err := queryInt(r, "limit", 50)
+		if err != nil || limit <= 0 || limit > maxWebhookDeliveries {
+			writeBadRequest(w, r, fmt.Sprintf("limit: ожидается число от 1 до %d", maxWebhookDeliveries))
+			return
+		}
+
+		deliveries, err := webhooks.ListDeliveries(r.Context(), id, limit)
+		if err != nil {
+			writeError(w, r, err, map[string]interface{}{"id": id})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"subscription_id": id,
+			"count":           len(deliveries),
+			"deliveries":      deliveries,
+		})
+	}
+}
+
+// POST /admin/webhooks/deliveries/{id}/redeliver - отправить повторно
+func webhookRedeliverHandler(webhooks webhook.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := webhookID(w, r)
+		if !ok {
+			return
+		}
+		if err := webhooks.Redeliver(r.Context(), id); err != nil {
+			writeError(w, r, err, map[string]interface{}{"delivery_id": id})
+			return
+		}
+		log.Printf("Доставка вебхука %d поставлена в очередь повторно", id)
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"queued": true, "delivery_id": id})
+	}
+}
+
+func webhookID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeBadRequest(w, r, "id: ожидается положительное число")
+		return 0, false
+	}
+	return id, true
+}
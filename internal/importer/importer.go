@@ -0,0 +1,299 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"order-service/internal/database"
+	"order-service/internal/service"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// максимальная длина строки NDJSON
+const maxLineSize = 16 * 1024 * 1024
+
+// параметры импорта
+type Options struct {
+	// заказов в одной транзакции
+	BatchSize int
+	// параллельно сохраняемых пачек
+	Workers int
+	// файл с номером последней полностью сохраненной строки, пустой - без возобновления
+	CheckpointPath string
+	// файл для отклоненных записей в формате NDJSON, пустой - только подсчет
+	RejectPath string
+}
+
+// итог импорта
+type Report struct {
+	Lines      int           `json:"lines"`
+	Skipped    int           `json:"skipped"`
+	Imported   int           `json:"imported"`
+	Duplicates int           `json:"duplicates"`
+	Invalid    int           `json:"invalid"`
+	Batches    int           `json:"batches"`
+	Duration   time.Duration `json:"duration_ns"`
+}
+
+// отклоненная запись
+type rejectRecord struct {
+	Line   int    `json:"line"`
+	Error  string `json:"error"`
+	Record string `json:"record"`
+}
+
+type batch struct {
+	seq      int
+	lastLine int
+	orders   []database.Order
+}
+
+type batchResult struct {
+	batch
+	result service.ImportResult
+	err    error
+}
+
+// импортирует заказы из NDJSON через ValidatorService и путь сохранения ProcessOrder
+type Importer struct {
+	processor service.OrderProcessor
+	opts      Options
+}
+
+// создает импортер
+func NewImporter(processor service.OrderProcessor, opts Options) *Importer {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	return &Importer{processor: processor, opts: opts}
+}
+
+// распаковывает вход, если он сжат gzip
+func OpenInput(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+	return buffered, nil
+}
+
+// читает заказы из r и сохраняет их пачками; при ошибке БД импорт останавливается,
+// а контрольная точка указывает на последнюю строку, до которой все сохранено
+func (im *Importer) Run(ctx context.Context, r io.Reader) (Report, error) {
+	start := time.Now()
+	report := Report{}
+
+	checkpoint, err := im.loadCheckpoint()
+	if err != nil {
+		return report, err
+	}
+	if checkpoint > 0 {
+		log.Printf("Импорт продолжается после строки %d", checkpoint)
+	}
+
+	var rejects io.Writer = io.Discard
+	if im.opts.RejectPath != "" {
+		file, err := os.OpenFile(im.opts.RejectPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return report, fmt.Errorf("ошибка открытия файла отклоненных записей: %v", err)
+		}
+		defer file.Close()
+		rejects = file
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan batch, im.opts.Workers)
+	results := make(chan batchResult, im.opts.Workers)
+
+	var workers sync.WaitGroup
+	for i := 0; i < im.opts.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for b := range batches {
+				res := batchResult{batch: b}
+				if len(b.orders) > 0 {
+					res.result, res.err = im.processor.ImportOrders(ctx, b.orders)
+				}
+				// останавливаем остальные пачки сразу, не дожидаясь обработки результата
+				if res.err != nil {
+					cancel()
+				}
+				results <- res
+			}
+		}()
+	}
+
+	startLine := checkpoint
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- im.read(ctx, r, startLine, rejects, batches, &report)
+		close(batches)
+		workers.Wait()
+		close(results)
+	}()
+
+	// пачки завершаются в произвольном порядке, контрольная точка двигается
+	// только по непрерывному префиксу завершенных пачек
+	var importErr error
+	completed := make(map[int]int)
+	nextSeq := 0
+	for res := range results {
+		if res.err != nil {
+			// отмена других пачек - следствие первой ошибки, в отчет попадает исходная
+			if importErr == nil || errors.Is(importErr, context.Canceled) {
+				importErr = fmt.Errorf("ошибка сохранения пачки до строки %d: %w", res.lastLine, res.err)
+			}
+			continue
+		}
+
+		report.Batches++
+		report.Imported += len(res.result.Imported)
+		report.Duplicates += len(res.result.Duplicates)
+
+		completed[res.seq] = res.lastLine
+		advanced := false
+		for lastLine, ok := completed[nextSeq]; ok; lastLine, ok = completed[nextSeq] {
+			delete(completed, nextSeq)
+			checkpoint = lastLine
+			nextSeq++
+			advanced = true
+		}
+		if advanced {
+			if err := im.saveCheckpoint(checkpoint); err != nil {
+				log.Printf("Ошибка сохранения контрольной точки: %v", err)
+			}
+		}
+	}
+
+	report.Duration = time.Since(start)
+	if err := <-readErr; err != nil && importErr == nil {
+		importErr = err
+	}
+	return report, importErr
+}
+
+// читает строки, отклоняет невалидные и отправляет пачки воркерам
+func (im *Importer) read(ctx context.Context, r io.Reader, checkpoint int, rejects io.Writer, batches chan<- batch, report *Report) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	encoder := json.NewEncoder(rejects)
+
+	current := batch{}
+	send := func() bool {
+		select {
+		case batches <- current:
+			current = batch{seq: current.seq + 1}
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		report.Lines++
+		if line <= checkpoint {
+			report.Skipped++
+			continue
+		}
+		current.lastLine = line
+
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var order database.Order
+		err := json.Unmarshal(data, &order)
+		if err != nil {
+			err = fmt.Errorf("ошибка парсинга JSON: %v", err)
+		} else if validationErr := im.processor.ValidateOrder(order); validationErr != nil {
+			err = fmt.Errorf("невалидный заказ: %v", validationErr)
+		}
+		if err != nil {
+			report.Invalid++
+			if err := encoder.Encode(rejectRecord{Line: line, Error: err.Error(), Record: string(data)}); err != nil {
+				return fmt.Errorf("ошибка записи отклоненной записи: %v", err)
+			}
+			continue
+		}
+
+		current.orders = append(current.orders, order)
+		if len(current.orders) >= im.opts.BatchSize && !send() {
+			return ctx.Err()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения строки %d: %v", line+1, err)
+	}
+
+	// последняя пачка может быть пустой: она нужна, чтобы контрольная точка дошла до конца файла
+	if current.lastLine > 0 && !send() {
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (im *Importer) loadCheckpoint() (int, error) {
+	if im.opts.CheckpointPath == "" {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(im.opts.CheckpointPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения контрольной точки: %v", err)
+	}
+
+	line, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || line < 0 {
+		return 0, fmt.Errorf("поврежденная контрольная точка %s", im.opts.CheckpointPath)
+	}
+	return line, nil
+}
+
+// пишем во временный файл и переименовываем, чтобы не оставить обрезанную контрольную точку
+func (im *Importer) saveCheckpoint(line int) error {
+	if im.opts.CheckpointPath == "" {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(im.opts.CheckpointPath), filepath.Base(im.opts.CheckpointPath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strconv.Itoa(line) + "\n"); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), im.opts.CheckpointPath)
+}
@@ -0,0 +1,163 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"order-service/internal/database"
+	"order-service/internal/service"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// процессор с хранилищем в памяти; заказ с пустым track_number считается невалидным
+type processorMock struct {
+	service.OrderProcessor
+	mutex  sync.Mutex
+	saved  map[string]bool
+	failOn string
+}
+
+func newProcessorMock() *processorMock {
+	return &processorMock{saved: make(map[string]bool)}
+}
+
+func (m *processorMock) ValidateOrder(order database.Order) error {
+	if order.TrackNumber == "" {
+		return fmt.Errorf("track_number обязателен")
+	}
+	return nil
+}
+
+func (m *processorMock) ImportOrders(ctx context.Context, orders []database.Order) (service.ImportResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// как и транзакция в БД, после отмены контекста пачка не сохраняется
+	if err := ctx.Err(); err != nil {
+		return service.ImportResult{}, err
+	}
+
+	var result service.ImportResult
+	for _, order := range orders {
+		if order.OrderUID == m.failOn {
+			return service.ImportResult{}, database.ErrUnavailable
+		}
+	}
+	for _, order := range orders {
+		if m.saved[order.OrderUID] {
+			result.Duplicates = append(result.Duplicates, order.OrderUID)
+			continue
+		}
+		m.saved[order.OrderUID] = true
+		result.Imported = append(result.Imported, order.OrderUID)
+	}
+	return result, nil
+}
+
+func ndjson(orders ...database.Order) string {
+	var buf strings.Builder
+	for _, order := range orders {
+		data, _ := json.Marshal(order)
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+func TestImporterReport(t *testing.T) {
+	dir := t.TempDir()
+	rejectPath := filepath.Join(dir, "rejected.ndjson")
+
+	input := ndjson(
+		database.Order{OrderUID: "imp1", TrackNumber: "T1"},
+		database.Order{OrderUID: "imp2", TrackNumber: "T2"},
+		database.Order{OrderUID: "imp1", TrackNumber: "T1"},
+		database.Order{OrderUID: "imp3"},
+	) + "{broken\n\n" + ndjson(database.Order{OrderUID: "imp4", TrackNumber: "T4"})
+
+	processor := newProcessorMock()
+	report, err := NewImporter(processor, Options{BatchSize: 2, Workers: 3, RejectPath: rejectPath}).
+		Run(context.Background(), strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Ошибка импорта: %v", err)
+	}
+
+	if report.Lines != 7 || report.Imported != 3 || report.Duplicates != 1 || report.Invalid != 2 {
+		t.Errorf("Неверный отчет: %+v", report)
+	}
+
+	data, err := os.ReadFile(rejectPath)
+	if err != nil {
+		t.Fatalf("Ошибка чтения файла отклоненных записей: %v", err)
+	}
+	var rejected []rejectRecord
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var record rejectRecord
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("Ошибка декодирования отклоненной записи: %v", err)
+		}
+		rejected = append(rejected, record)
+	}
+	if len(rejected) != 2 || rejected[0].Line != 4 || rejected[1].Line != 5 {
+		t.Errorf("Неверные отклоненные записи: %+v", rejected)
+	}
+}
+
+func TestImporterCheckpointResume(t *testing.T) {
+	checkpointPath := filepath.Join(t.TempDir(), "import.checkpoint")
+
+	var orders []database.Order
+	for i := 0; i < 10; i++ {
+		orders = append(orders, database.Order{OrderUID: fmt.Sprintf("ord%d", i), TrackNumber: "T"})
+	}
+	input := ndjson(orders...)
+
+	// БД отказывает на седьмом заказе: пачки с 1 по 6 строку сохранены
+	processor := newProcessorMock()
+	processor.failOn = "ord6"
+	opts := Options{BatchSize: 2, Workers: 1, CheckpointPath: checkpointPath}
+	if _, err := NewImporter(processor, opts).Run(context.Background(), strings.NewReader(input)); err == nil {
+		t.Fatal("Ожидалась ошибка импорта")
+	}
+
+	data, err := os.ReadFile(checkpointPath)
+	if err != nil {
+		t.Fatalf("Контрольная точка не сохранена: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "6" {
+		t.Errorf("Ожидалась контрольная точка 6, получено %s", data)
+	}
+
+	// повторный запуск продолжает после контрольной точки
+	processor.failOn = ""
+	report, err := NewImporter(processor, opts).Run(context.Background(), strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Ошибка повторного импорта: %v", err)
+	}
+	if report.Skipped != 6 || report.Imported != 4 || len(processor.saved) != 10 {
+		t.Errorf("Неверный отчет повторного импорта: %+v", report)
+	}
+}
+
+func TestOpenInputGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(ndjson(database.Order{OrderUID: "gz1", TrackNumber: "T"})))
+	gz.Close()
+
+	input, err := OpenInput(&buf)
+	if err != nil {
+		t.Fatalf("Ошибка открытия входа: %v", err)
+	}
+	report, err := NewImporter(newProcessorMock(), Options{}).Run(context.Background(), input)
+	if err != nil || report.Imported != 1 {
+		t.Errorf("Ожидался импорт 1 заказа из gzip, получено %+v (%v)", report, err)
+	}
+}
@@ -0,0 +1,79 @@
+package openapi
+
+import (
+	"testing"
+	"time"
+)
+
+type testItem struct {
+	Name  string `json:"name" validate:"required,min=2"`
+	Price int    `json:"price" validate:"required,min=1"`
+}
+
+type testOrder struct {
+	ID       string     `json:"id" validate:"required,alphanumdash"`
+	Currency string     `json:"currency" validate:"required,alpha,uppercase,min=3,max=3"`
+	Email    string     `json:"email" validate:"required,email"`
+	Tags     []string   `json:"tags" validate:"omitempty,dive,min=1"`
+	Items    []testItem `json:"items" validate:"required,min=1,dive"`
+	Note     string     `json:"note"`
+	Created  time.Time  `json:"created"`
+	Secret   string     `json:"-"`
+	internal string
+}
+
+func TestGeneratorSchema(t *testing.T) {
+	gen := NewGenerator()
+	ref := gen.Schema(testOrder{})
+	if ref.Ref != "#/components/schemas/testOrder" {
+		t.Fatalf("Ожидалась ссылка на testOrder, получено %q", ref.Ref)
+	}
+
+	order := gen.Components()["testOrder"]
+	if order == nil {
+		t.Fatal("Схема testOrder не добавлена в components")
+	}
+	if len(order.Properties) != 7 {
+		t.Errorf("Ожидалось 7 полей, получено %d", len(order.Properties))
+	}
+	if _, ok := order.Properties["Secret"]; ok {
+		t.Error("Поле с json:\"-\" не должно попадать в схему")
+	}
+
+	required := map[string]bool{}
+	for _, name := range order.Required {
+		required[name] = true
+	}
+	if !required["id"] || !required["items"] || required["note"] || required["tags"] {
+		t.Errorf("Неверный список обязательных полей: %v", order.Required)
+	}
+
+	currency := order.Properties["currency"]
+	if currency.Pattern != tagPatterns["alpha"] || len(currency.AllOf) != 1 {
+		t.Errorf("Ожидались оба pattern для currency: %+v", currency)
+	}
+	if *currency.MinLength != 3 || *currency.MaxLength != 3 {
+		t.Errorf("Ожидалась длина 3 для currency")
+	}
+	if order.Properties["email"].Format != "email" {
+		t.Errorf("Ожидался format email")
+	}
+	if order.Properties["created"].Format != "date-time" {
+		t.Errorf("Ожидался format date-time для времени")
+	}
+
+	// правила до dive относятся к массиву, после - к элементам
+	items := order.Properties["items"]
+	if items.MinItems == nil || *items.MinItems != 1 || items.Items.Ref != "#/components/schemas/testItem" {
+		t.Errorf("Неверная схема items: %+v", items)
+	}
+	tags := order.Properties["tags"]
+	if tags.MinItems != nil || tags.Items.MinLength == nil || *tags.Items.MinLength != 1 {
+		t.Errorf("Неверная схема tags: %+v", tags)
+	}
+
+	item := gen.Components()["testItem"]
+	if item == nil || *item.Properties["price"].Minimum != 1 {
+		t.Errorf("Ожидался minimum 1 для price: %+v", item)
+	}
+}
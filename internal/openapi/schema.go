@@ -0,0 +1,200 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// регулярные выражения для правил validator; alphanumdash совпадает с регистрацией в ValidatorService
+var tagPatterns = map[string]string{
+	"alpha":        `^[a-zA-Z]+$`,
+	"alphanum":     `^[a-zA-Z0-9]+$`,
+	"alphanumdash": `^[a-zA-Z0-9\-_]+$`,
+	"alphaunicode": `^\p{L}+$`,
+	"numeric":      `^[-+]?[0-9]+(?:\.[0-9]+)?$`,
+	"e164":         `^\+[1-9][0-9]{1,14}$`,
+	"uppercase":    `^[^a-z]*$`,
+}
+
+// форматы OpenAPI для правил validator
+var tagFormats = map[string]string{
+	"email": "email",
+	"uuid":  "uuid",
+	"url":   "uri",
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// строит схемы по Go типам: имена полей из тегов json, ограничения из тегов validate;
+// именованные структуры попадают в components и подставляются ссылкой
+type Generator struct {
+	schemas map[string]*Schema
+}
+
+func NewGenerator() *Generator {
+	return &Generator{schemas: make(map[string]*Schema)}
+}
+
+// схема для значения v; для структуры возвращается ссылка на components
+func (g *Generator) Schema(v interface{}) *Schema {
+	return g.schemaFor(reflect.TypeOf(v))
+}
+
+// добавляет в components схему, собранную вручную
+func (g *Generator) Define(name string, schema *Schema) *Schema {
+	g.schemas[name] = schema
+	return Ref(name)
+}
+
+// все собранные схемы
+func (g *Generator) Components() map[string]*Schema {
+	return g.schemas
+}
+
+func (g *Generator) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			// резервируем имя до обхода полей, чтобы не зациклиться на рекурсивных типах
+			g.schemas[t.Name()] = nil
+			g.schemas[t.Name()] = g.structSchema(t)
+		}
+		return Ref(t.Name())
+	default:
+		return &Schema{}
+	}
+}
+
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schemaFor(field.Type)
+		required := applyValidateTag(property, field.Tag.Get("validate"))
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// переносит правила validate в схему; возвращает, обязательно ли поле
+func applyValidateTag(schema *Schema, tag string) bool {
+	if tag == "" || tag == "-" {
+		return false
+	}
+
+	target := schema
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			// правила до dive относятся к самому полю, поэтому required учитываем только там
+			if target == schema {
+				required = true
+			}
+		case "dive":
+			// дальнейшие правила относятся к элементам массива; у структур свои теги
+			if target.Items == nil || target.Items.Ref != "" {
+				return required
+			}
+			target = target.Items
+		case "min", "max", "len":
+			limitSchema(target, name, param)
+		case "oneof":
+			target.Enum = strings.Fields(param)
+		default:
+			if format, ok := tagFormats[name]; ok {
+				target.Format = format
+			} else if pattern, ok := tagPatterns[name]; ok {
+				addPattern(target, pattern)
+			}
+		}
+	}
+	return required
+}
+
+// min/max/len означают длину строки, размер массива или значение числа
+func limitSchema(schema *Schema, rule, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	size := int(n)
+
+	switch schema.Type {
+	case "string":
+		if rule != "max" {
+			schema.MinLength = &size
+		}
+		if rule != "min" {
+			schema.MaxLength = &size
+		}
+	case "array":
+		if rule != "max" {
+			schema.MinItems = &size
+		}
+		if rule != "min" {
+			schema.MaxItems = &size
+		}
+	case "integer", "number":
+		if rule != "max" {
+			schema.Minimum = &n
+		}
+		if rule != "min" {
+			schema.Maximum = &n
+		}
+	}
+}
+
+// в схеме одно поле pattern, остальные выражения добавляются через allOf
+func addPattern(schema *Schema, pattern string) {
+	if schema.Pattern == "" {
+		schema.Pattern = pattern
+		return
+	}
+	schema.AllOf = append(schema.AllOf, &Schema{Pattern: pattern})
+}
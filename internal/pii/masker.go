@@ -0,0 +1,200 @@
+package pii
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"order-service/internal/database"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// поля заказа с персональными данными; имена совпадают с путями в JSON
+const (
+	FieldDeliveryName       = "delivery.name"
+	FieldDeliveryPhone      = "delivery.phone"
+	FieldDeliveryEmail      = "delivery.email"
+	FieldDeliveryAddress    = "delivery.address"
+	FieldPaymentTransaction = "payment.transaction"
+)
+
+var fields = []string{FieldDeliveryName, FieldDeliveryPhone, FieldDeliveryEmail, FieldDeliveryAddress, FieldPaymentTransaction}
+
+// способ маскирования значения
+type Strategy string
+
+const (
+	// значение не маскируется
+	StrategyNone Strategy = "none"
+	// значение заменяется на ***
+	StrategyRedact Strategy = "redact"
+	// остается первая буква каждого слова: Иван Петров -> И*** П*****
+	StrategyPartial Strategy = "partial"
+	// остается первая буква имени и домен: ivan@example.com -> i***@example.com
+	StrategyEmail Strategy = "email"
+	// остаются последние 4 символа и разделители: +79161234567 -> +*******4567
+	StrategyLast4 Strategy = "last4"
+	// короткий SHA-256: одинаковые значения можно сопоставить, не раскрывая их
+	StrategyHash Strategy = "hash"
+)
+
+var strategies = []Strategy{StrategyNone, StrategyRedact, StrategyPartial, StrategyEmail, StrategyLast4, StrategyHash}
+
+const redacted = "***"
+
+// правило для каждого поля
+type Rules map[string]Strategy
+
+// правила по умолчанию: из маски понятно, что значение есть и какого оно вида
+func DefaultRules() Rules {
+	return Rules{
+		FieldDeliveryName:       StrategyPartial,
+		FieldDeliveryPhone:      StrategyLast4,
+		FieldDeliveryEmail:      StrategyEmail,
+		FieldDeliveryAddress:    StrategyRedact,
+		FieldPaymentTransaction: StrategyLast4,
+	}
+}
+
+// разбирает список "поле=правило" через запятую поверх правил по умолчанию
+func ParseRules(spec string) (Rules, error) {
+	rules := DefaultRules()
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		field, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("правило %q: ожидается формат поле=правило", entry)
+		}
+		field, strategy := strings.TrimSpace(field), Strategy(strings.TrimSpace(value))
+		if !slices.Contains(fields, field) {
+			return nil, fmt.Errorf("неизвестное поле %q: допустимы %s", field, strings.Join(fields, ", "))
+		}
+		if !slices.Contains(strategies, strategy) {
+			return nil, fmt.Errorf("поле %s: неизвестное правило %q", field, strategy)
+		}
+		rules[field] = strategy
+	}
+	return rules, nil
+}
+
+type masker struct {
+	rules Rules
+}
+
+// создает маскировщик; поля без правила не маскируются
+func NewMasker(rules Rules) Masker {
+	return &masker{rules: rules}
+}
+
+func (m *masker) MaskOrder(order database.Order) database.Order {
+	order.Delivery.Name = m.Mask(FieldDeliveryName, order.Delivery.Name)
+	order.Delivery.Phone = m.Mask(FieldDeliveryPhone, order.Delivery.Phone)
+	order.Delivery.Email = m.Mask(FieldDeliveryEmail, order.Delivery.Email)
+	order.Delivery.Address = m.Mask(FieldDeliveryAddress, order.Delivery.Address)
+	order.Payment.Transaction = m.Mask(FieldPaymentTransaction, order.Payment.Transaction)
+	return order
+}
+
+func (m *masker) Mask(field, value string) string {
+	if value == "" {
+		return value
+	}
+	switch m.rules[field] {
+	case StrategyRedact:
+		return redacted
+	case StrategyPartial:
+		return maskWords(value)
+	case StrategyEmail:
+		return maskEmail(value)
+	case StrategyLast4:
+		return maskAllButLast(value, 4)
+	case StrategyHash:
+		sum := sha256.Sum256([]byte(value))
+		return "#" + hex.EncodeToString(sum[:6])
+	default:
+		return value
+	}
+}
+
+// сообщение разбирается без схемы: маскируются поля с правилами, остальное сохраняется как есть
+func (m *masker) MaskJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// большие числа не должны превращаться в float64
+	decoder.UseNumber()
+	var document map[string]interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		parent, key, _ := strings.Cut(field, ".")
+		object, ok := document[parent].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value, ok := object[key].(string); ok {
+			object[key] = m.Mask(field, value)
+		}
+	}
+	return json.Marshal(document)
+}
+
+func maskWords(value string) string {
+	var b strings.Builder
+	first := true
+	for _, r := range value {
+		switch {
+		case unicode.IsSpace(r):
+			first = true
+			b.WriteRune(r)
+		case first:
+			first = false
+			b.WriteRune(r)
+		default:
+			b.WriteByte('*')
+		}
+	}
+	return b.String()
+}
+
+func maskEmail(value string) string {
+	at := strings.LastIndexByte(value, '@')
+	if at <= 0 {
+		return maskWords(value)
+	}
+	first, _ := utf8.DecodeRuneInString(value)
+	return string(first) + redacted + value[at:]
+}
+
+// буквы и цифры, кроме последних keep, заменяются на *; короткие значения скрываются целиком
+func maskAllButLast(value string, keep int) string {
+	runes := []rune(value)
+	significant := 0
+	for _, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			significant++
+		}
+	}
+	if significant <= keep*2 {
+		return redacted
+	}
+
+	for i := len(runes) - 1; i >= 0; i-- {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			continue
+		}
+		if keep > 0 {
+			keep--
+			continue
+		}
+		runes[i] = '*'
+	}
+	return string(runes)
+}
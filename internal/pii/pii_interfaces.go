@@ -0,0 +1,13 @@
+package pii
+
+import "order-service/internal/database"
+
+// маскирует персональные данные покупателя по правилам для каждого поля
+type Masker interface {
+	// копия заказа с замаскированными полями; исходный заказ не меняется
+	MaskOrder(order database.Order) database.Order
+	// значение поля field, например delivery.phone; поле без правила возвращается как есть
+	Mask(field, value string) string
+	// JSON заказа с замаскированными полями, для вывода сырых сообщений в лог
+	MaskJSON(data []byte) ([]byte, error)
+}
@@ -0,0 +1,117 @@
+package pii
+
+import (
+	"encoding/json"
+	"order-service/internal/database"
+	"strings"
+	"testing"
+)
+
+func testOrder() database.Order {
+	return database.Order{
+		OrderUID: "b563feb7b2b84b6test",
+		Delivery: database.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Email:   "test@gmail.com",
+			Address: "Ploshad Mira 15",
+			City:    "Kiryat Mozkin",
+		},
+		Payment: database.Payment{Transaction: "b563feb7-b2b8-4b6a-9f5e-2f6a4c1d8e90", Amount: 1817},
+	}
+}
+
+func TestMaskOrderDefaultRules(t *testing.T) {
+	order := testOrder()
+	masked := NewMasker(DefaultRules()).MaskOrder(order)
+
+	expected := map[string][2]string{
+		"name":        {masked.Delivery.Name, "T*** T*****"},
+		"phone":       {masked.Delivery.Phone, "+******0000"},
+		"email":       {masked.Delivery.Email, "t***@gmail.com"},
+		"address":     {masked.Delivery.Address, "***"},
+		"transaction": {masked.Payment.Transaction, "********-****-****-****-********8e90"},
+	}
+	for field, values := range expected {
+		if values[0] != values[1] {
+			t.Errorf("%s: ожидалось %q, получено %q", field, values[1], values[0])
+		}
+	}
+
+	// остальные поля и исходный заказ не меняются
+	if masked.Delivery.City != order.Delivery.City || masked.Payment.Amount != order.Payment.Amount {
+		t.Errorf("Изменены поля без правил: %+v", masked)
+	}
+	if order.Delivery.Name != "Test Testov" {
+		t.Error("Исходный заказ изменен")
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("delivery.name=redact, payment.transaction=none,delivery.email=hash")
+	if err != nil {
+		t.Fatalf("Ошибка разбора правил: %v", err)
+	}
+	masker := NewMasker(rules)
+	order := masker.MaskOrder(testOrder())
+
+	if order.Delivery.Name != "***" || order.Payment.Transaction != testOrder().Payment.Transaction {
+		t.Errorf("Правила не применены: %+v", order)
+	}
+	// одинаковые значения дают одинаковый хэш
+	if !strings.HasPrefix(order.Delivery.Email, "#") || masker.Mask(FieldDeliveryEmail, "test@gmail.com") != order.Delivery.Email {
+		t.Errorf("Неверный хэш email: %q", order.Delivery.Email)
+	}
+	// поля без переопределения используют правила по умолчанию
+	if order.Delivery.Phone != "+******0000" {
+		t.Errorf("Ожидалось правило по умолчанию для телефона, получено %q", order.Delivery.Phone)
+	}
+
+	for _, spec := range []string{"delivery.name", "delivery.city=redact", "delivery.name=blur"} {
+		if _, err := ParseRules(spec); err == nil {
+			t.Errorf("%q: ожидалась ошибка", spec)
+		}
+	}
+}
+
+func TestMaskEdgeCases(t *testing.T) {
+	masker := NewMasker(DefaultRules())
+	tests := []struct {
+		field, value, expected string
+	}{
+		{FieldDeliveryName, "", ""},
+		{FieldDeliveryName, "Иван Петров", "И*** П*****"},
+		{FieldDeliveryEmail, "not-an-email", "n***********"},
+		{FieldDeliveryPhone, "+7123", "***"},
+		{"delivery.city", "Moscow", "Moscow"},
+	}
+	for _, tt := range tests {
+		if got := masker.Mask(tt.field, tt.value); got != tt.expected {
+			t.Errorf("%s %q: ожидалось %q, получено %q", tt.field, tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestMaskJSON(t *testing.T) {
+	message := []byte(`{"order_uid":"abc","delivery":{"name":"Test Testov","email":"test@gmail.com","zip":"2639809"},"payment":{"amount":1817,"payment_dt":1637907727123456789},"items":[]}`)
+	masked, err := NewMasker(DefaultRules()).MaskJSON(message)
+	if err != nil {
+		t.Fatalf("Ошибка маскирования: %v", err)
+	}
+
+	text := string(masked)
+	for _, secret := range []string{"Test Testov", "test@gmail.com"} {
+		if strings.Contains(text, secret) {
+			t.Errorf("В результате осталось %q: %s", secret, text)
+		}
+	}
+	var document map[string]interface{}
+	json.Unmarshal(masked, &document)
+	if document["order_uid"] != "abc" || !strings.Contains(text, `"zip":"2639809"`) || !strings.Contains(text, "1637907727123456789") {
+		t.Errorf("Поля без правил изменены: %s", text)
+	}
+
+	if _, err := NewMasker(DefaultRules()).MaskJSON([]byte("Test Testov, test@gmail.com")); err == nil {
+		t.Error("Ожидалась ошибка для сообщения не в JSON")
+	}
+}
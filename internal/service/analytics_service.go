@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"order-service/internal/database"
+)
+
+// возвращает показатели продаж по периодам
+func (s *OrderServiceImpl) GetSalesStats(ctx context.Context, filter database.AnalyticsFilter) ([]database.SalesPoint, error) {
+	points, err := s.repo.GetSalesStats(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения статистики продаж: %w", err)
+	}
+	return points, nil
+}
+
+// возвращает бренды с наибольшей выручкой
+func (s *OrderServiceImpl) GetTopBrands(ctx context.Context, filter database.AnalyticsFilter, limit int) ([]database.TopEntry, error) {
+	entries, err := s.repo.GetTopBrands(ctx, filter, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения топа брендов: %w", err)
+	}
+	return entries, nil
+}
+
+// возвращает товары с наибольшей выручкой
+func (s *OrderServiceImpl) GetTopProducts(ctx context.Context, filter database.AnalyticsFilter, limit int) ([]database.TopEntry, error) {
+	entries, err := s.repo.GetTopProducts(ctx, filter, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения топа товаров: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"order-service/internal/database"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ограничения бенчмарка, чтобы один запрос не занял БД надолго
+const (
+	DefaultBenchmarkIterations = 1000
+	MaxBenchmarkIterations     = 100000
+	MaxBenchmarkConcurrency    = 64
+)
+
+// параметры бенчмарка; нулевые значения заменяются значениями по умолчанию
+type BenchmarkOptions struct {
+	Iterations  int
+	Concurrency int
+}
+
+// распределение задержек одного пути чтения
+type LatencyStats struct {
+	Requests int     `json:"requests"`
+	TotalMs  float64 `json:"total_ms"`
+	MeanMs   float64 `json:"mean_ms"`
+	P50Ms    float64 `json:"p50_ms"`
+	P95Ms    float64 `json:"p95_ms"`
+	P99Ms    float64 `json:"p99_ms"`
+	MaxMs    float64 `json:"max_ms"`
+	// запросов в секунду с учетом параллельности
+	Throughput float64 `json:"throughput_rps"`
+}
+
+// результат сравнения чтения из кэша и из БД
+type BenchmarkResult struct {
+	OrderUID    string       `json:"order_uid"`
+	Iterations  int          `json:"iterations"`
+	Concurrency int          `json:"concurrency"`
+	Cache       LatencyStats `json:"cache"`
+	DB          LatencyStats `json:"db"`
+	// во сколько раз кэш быстрее БД по медиане
+	Speedup float64 `json:"speedup"`
+}
+
+// сравнивает задержки чтения заказа из кэша и из БД
+func (s *OrderServiceImpl) RunBenchmark(ctx context.Context, orderUID string, opts BenchmarkOptions) (BenchmarkResult, error) {
+	if opts.Iterations == 0 {
+		opts.Iterations = DefaultBenchmarkIterations
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = 1
+	}
+	if opts.Iterations < 1 || opts.Iterations > MaxBenchmarkIterations {
+		return BenchmarkResult{}, fmt.Errorf("%w: iterations должен быть от 1 до %d", database.ErrInvalidArgument, MaxBenchmarkIterations)
+	}
+	if opts.Concurrency < 1 || opts.Concurrency > MaxBenchmarkConcurrency {
+		return BenchmarkResult{}, fmt.Errorf("%w: concurrency должен быть от 1 до %d", database.ErrInvalidArgument, MaxBenchmarkConcurrency)
+	}
+
+	// заказ должен существовать; GetOrder заодно кладет его в кэш
+	if _, err := s.GetOrder(orderUID); err != nil {
+		return BenchmarkResult{}, err
+	}
+
+	result := BenchmarkResult{OrderUID: orderUID, Iterations: opts.Iterations, Concurrency: opts.Concurrency}
+
+	var err error
+	// Peek не засчитывает синтетические чтения в статистику кэша и не продлевает запись
+	result.Cache, err = measureLatency(ctx, opts, func() error {
+		if _, found := s.cache.Peek(orderUID); !found {
+			return fmt.Errorf("заказ %s: %w", orderUID, database.ErrOrderNotFound)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("бенчмарк кэша: %w", err)
+	}
+
+	result.DB, err = measureLatency(ctx, opts, func() error {
+		_, err := s.repo.GetOrder(orderUID)
+		return err
+	})
+	if err != nil {
+		return result, fmt.Errorf("бенчмарк БД: %w", err)
+	}
+
+	if result.Cache.P50Ms > 0 {
+		result.Speedup = result.DB.P50Ms / result.Cache.P50Ms
+	}
+	return result, nil
+}
+
+// выполняет fn opts.Iterations раз в opts.Concurrency горутинах; первая ошибка прерывает замер
+func measureLatency(ctx context.Context, opts BenchmarkOptions, fn func() error) (LatencyStats, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	latencies := make([]time.Duration, opts.Iterations)
+	var next atomic.Int64
+	var firstErr error
+	var errOnce sync.Once
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for range opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= opts.Iterations {
+					return
+				}
+				opStart := time.Now()
+				if err := fn(); err != nil {
+					errOnce.Do(func() { firstErr = err })
+					cancel()
+					return
+				}
+				latencies[i] = time.Since(opStart)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if firstErr != nil {
+		return LatencyStats{}, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return LatencyStats{}, err
+	}
+	return latencyStats(latencies, elapsed), nil
+}
+
+func latencyStats(latencies []time.Duration, elapsed time.Duration) LatencyStats {
+	slices.Sort(latencies)
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+
+	stats := LatencyStats{
+		Requests: len(latencies),
+		TotalMs:  durationMs(elapsed),
+		MeanMs:   durationMs(total) / float64(len(latencies)),
+		P50Ms:    durationMs(percentile(latencies, 50)),
+		P95Ms:    durationMs(percentile(latencies, 95)),
+		P99Ms:    durationMs(percentile(latencies, 99)),
+		MaxMs:    durationMs(latencies[len(latencies)-1]),
+	}
+	if elapsed > 0 {
+		stats.Throughput = float64(len(latencies)) / elapsed.Seconds()
+	}
+	return stats
+}
+
+// перцентиль по методу ближайшего ранга; latencies отсортированы
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(latencies))))
+	return latencies[max(rank-1, 0)]
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"order-service/internal/database"
+	"time"
+)
+
+// запись кэша для администрирования
+type CacheEntryInfo struct {
+	OrderUID   string    `json:"order_uid"`
+	CachedAt   time.Time `json:"cached_at,omitzero"`
+	AgeSeconds float64   `json:"age_seconds"`
+}
+
+// результат прогрева кэша списком заказов
+type CacheWarmResult struct {
+	Warmed   []string          `json:"warmed"`
+	NotFound []string          `json:"not_found"`
+	Failed   map[string]string `json:"failed"`
+}
+
+// возвращает страницу записей локального кэша, сначала самые новые;
+// при двухуровневом кэше записи Redis в список не входят
+func (s *OrderServiceImpl) ListCacheEntries(offset, limit int) ([]CacheEntryInfo, int) {
+	now := time.Now()
+	cached, total := s.cache.Entries(offset, limit)
+
+	entries := make([]CacheEntryInfo, len(cached))
+	for i, entry := range cached {
+		entries[i] = CacheEntryInfo{OrderUID: entry.Order.OrderUID}
+		if !entry.CreatedAt.IsZero() {
+			entries[i].CachedAt = entry.CreatedAt
+			entries[i].AgeSeconds = now.Sub(entry.CreatedAt).Seconds()
+		}
+	}
+	return entries, total
+}
+
+// удаляет заказ из кэша, возвращает false если его там не было
+func (s *OrderServiceImpl) EvictCacheEntry(orderUID string) bool {
+	return s.cache.Delete(orderUID)
+}
+
+// полностью очищает кэш всех уровней и кэш отсутствующих заказов,
+// возвращает количество удаленных записей кэша заказов
+func (s *OrderServiceImpl) FlushCache() int {
+	if s.negativeCache != nil {
+		s.negativeCache.Clear()
+	}
+	return s.cache.Flush()
+}
+
+// загружает указанные заказы из БД в кэш
+func (s *OrderServiceImpl) WarmCacheOrders(orderUIDs []string) CacheWarmResult {
+	result := CacheWarmResult{
+		Warmed:   []string{},
+		NotFound: []string{},
+		Failed:   map[string]string{},
+	}
+
+	for _, orderUID := range orderUIDs {
+		order, err := s.repo.GetOrder(orderUID)
+		switch {
+		case errors.Is(err, database.ErrOrderNotFound):
+			result.NotFound = append(result.NotFound, orderUID)
+		case err != nil:
+			result.Failed[orderUID] = err.Error()
+		default:
+			s.cache.Set(order)
+			if s.negativeCache != nil {
+				s.negativeCache.Remove(orderUID)
+			}
+			result.Warmed = append(result.Warmed, orderUID)
+		}
+	}
+
+	return result
+}
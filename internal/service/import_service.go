@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"order-service/internal/database"
+)
+
+// результат импорта пачки заказов
+type ImportResult struct {
+	Imported   []string
+	Duplicates []string
+}
+
+// сохраняет пачку уже проверенных заказов одной транзакцией тем же путем, что и ProcessOrder;
+// заказы, которые уже есть в БД, пропускаются без отката остальных.
+// Кэш не заполняется, чтобы массовый импорт не вытеснял горячие заказы
+func (s *OrderServiceImpl) ImportOrders(ctx context.Context, orders []database.Order) (ImportResult, error) {
+	var result ImportResult
+
+	tx, err := s.repo.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, order := range orders {
+		err := database.WithSavepoint(ctx, tx, func() error {
+			return s.saveOrder(tx, order)
+		})
+		switch {
+		case errors.Is(err, database.ErrDuplicateOrder):
+			result.Duplicates = append(result.Duplicates, order.OrderUID)
+		case err != nil:
+			return ImportResult{}, fmt.Errorf("заказ %s: %w", order.OrderUID, err)
+		default:
+			result.Imported = append(result.Imported, order.OrderUID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ImportResult{}, fmt.Errorf("ошибка коммита транзакции: %w", err)
+	}
+
+	if s.negativeCache != nil {
+		for _, orderUID := range result.Imported {
+			s.negativeCache.Remove(orderUID)
+		}
+	}
+	return result, nil
+}
@@ -1,95 +1,198 @@
 package service
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"order-service/internal/cache"
 	"order-service/internal/database"
+	"order-service/internal/pii"
+	"sort"
+	"sync"
 	"time"
 )
 
 // реализация интерфейса OrderService
 type OrderServiceImpl struct {
-	repo      database.OrderRepository
-	cache     cache.Cache
-	validator *ValidatorService
+	repo          database.OrderRepository
+	cache         cache.Cache
+	negativeCache cache.NegativeCache
+	validator     *ValidatorService
+	warmup        cache.CacheRestorer
+	listeners     []OrderListener
+	outboxes      []OrderOutbox
+	// персональные данные покупателя выводятся в лог только через него
+	masker pii.Masker
+	mutex  sync.RWMutex
 }
 
 // создает новый сервис заказов
-func NewOrderService(repo database.OrderRepository, cache cache.Cache) *OrderServiceImpl {
+func NewOrderService(repo database.OrderRepository, cache cache.Cache, negativeCache cache.NegativeCache) *OrderServiceImpl {
 	return &OrderServiceImpl{
-		repo:      repo,
-		cache:     cache,
-		validator: NewValidatorService(),
+		repo:          repo,
+		cache:         cache,
+		negativeCache: negativeCache,
+		validator:     NewValidatorService(),
+		masker:        pii.NewMasker(pii.DefaultRules()),
 	}
 }
 
-// обрабатывает входящее сообщение с заказом
-func (s *OrderServiceImpl) ProcessOrder(message []byte) error {
+// заменяет правила маскирования персональных данных в логах
+func (s *OrderServiceImpl) SetMasker(masker pii.Masker) {
+	s.masker = masker
+}
+
+// подписывает получателя на сохраненные заказы; вызывать до запуска обработки
+func (s *OrderServiceImpl) AddOrderListener(listener OrderListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// добавляет запись событий в транзакцию сохранения заказа; вызывать до запуска обработки
+func (s *OrderServiceImpl) AddOrderOutbox(outbox OrderOutbox) {
+	s.outboxes = append(s.outboxes, outbox)
+}
+
+var (
+	// пустое тело сообщения
+	ErrEmptyMessage = errors.New("пустое сообщение")
+	// тело сообщения не является JSON заказа
+	ErrMalformedOrder = errors.New("ошибка парсинга JSON")
+)
+
+// разбирает и проверяет заказ без сохранения; ошибка валидации - *ValidationError
+func (s *OrderServiceImpl) ParseOrder(message []byte) (database.Order, error) {
+	return s.parseOrder(message, s.validator.ValidateOrder)
+}
+
+// как ParseOrder, но дополнительно сверяет суммы платежа с товарами;
+// этими же правилами проверяется заказ в SubmitOrder
+func (s *OrderServiceImpl) CheckOrder(message []byte) (database.Order, error) {
+	return s.parseOrder(message, s.validator.ValidateOrderStrict)
+}
+
+func (s *OrderServiceImpl) parseOrder(message []byte, validate func(database.Order) error) (database.Order, error) {
 	var order database.Order
 
 	if len(message) == 0 {
-		return fmt.Errorf("пустое сообщение")
+		return order, ErrEmptyMessage
 	}
 
 	if err := json.Unmarshal(message, &order); err != nil {
-		log.Printf("Ошибка парсинга JSON: %v\n", err)
-		log.Printf("Содержимое сообщения: %s\n", string(message))
-		return fmt.Errorf("ошибка парсинга JSON: %v", err)
+		return order, fmt.Errorf("%w: %w", ErrMalformedOrder, err)
 	}
 
-	if err := s.ValidateOrder(order); err != nil {
-		log.Printf("Невалидный заказ: %v\n", err)
-		log.Printf("Данные заказа: %+v\n", order)
-		return fmt.Errorf("невалидный заказ: %v", err)
+	if err := validate(order); err != nil {
+		return order, fmt.Errorf("невалидный заказ: %w", err)
+	}
+	return order, nil
+}
+
+// обрабатывает входящее сообщение с заказом из Kafka или импорта
+func (s *OrderServiceImpl) ProcessOrder(message []byte) error {
+	_, err := s.processOrder(message, s.ParseOrder)
+	return err
+}
+
+// сохраняет заказ, принятый по HTTP; проверки совпадают с CheckOrder,
+// поэтому заказ, прошедший проверку без сохранения, не будет отклонен
+func (s *OrderServiceImpl) SubmitOrder(message []byte) (database.Order, error) {
+	return s.processOrder(message, s.CheckOrder)
+}
+
+func (s *OrderServiceImpl) processOrder(message []byte, parse func(message []byte) (database.Order, error)) (database.Order, error) {
+	order, err := parse(message)
+	if err != nil {
+		if !errors.Is(err, ErrEmptyMessage) {
+			log.Printf("Заказ отклонен: %v\n", err)
+			s.logMessage(message)
+		}
+		return order, err
 	}
 
 	// получаем соединение из репозитория
 	db := s.repo.GetDB()
 	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("ошибка начала транзакции: %v", err)
+		return order, fmt.Errorf("ошибка начала транзакции: %w", err)
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-			log.Printf("Транзакция откачена: %s\n", order.OrderUID)
-		}
-	}()
 
-	// сохраняем заказ через репозиторий
-	if err := s.repo.SaveOrder(tx, order); err != nil {
-		return fmt.Errorf("ошибка сохранения заказа: %v", err)
-	}
+	// время изменения ставим сами, чтобы заказ в кэше совпадал с прочитанным из БД;
+	// колонка TIMESTAMP хранит микросекунды без часового пояса
+	order.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
 
-	if err := s.repo.SaveDelivery(tx, order); err != nil {
-		return fmt.Errorf("ошибка сохранения доставки: %v", err)
-	}
-
-	if err := s.repo.SavePayment(tx, order); err != nil {
-		return fmt.Errorf("ошибка сохранения платежа: %v", err)
+	// сохраняем заказ через репозиторий
+	if err := s.saveOrder(tx, order); err != nil {
+		tx.Rollback()
+		log.Printf("Транзакция откачена: %s\n", order.OrderUID)
+		return order, err
 	}
 
-	if err := s.repo.SaveItems(tx, order); err != nil {
-		return fmt.Errorf("ошибка сохранения товаров: %v", err)
+	for _, outbox := range s.outboxes {
+		if err := outbox.EnqueueOrder(context.Background(), tx, order); err != nil {
+			tx.Rollback()
+			log.Printf("Транзакция откачена: %s\n", order.OrderUID)
+			return order, fmt.Errorf("ошибка записи событий заказа: %w", err)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+		return order, fmt.Errorf("ошибка коммита транзакции: %w", err)
 	}
 
 	// сохраняем в кэш
 	s.cache.Set(order)
+	if s.negativeCache != nil {
+		s.negativeCache.Remove(order.OrderUID)
+	}
+
+	for _, listener := range s.listeners {
+		listener.OrderIngested(order)
+	}
 
 	fmt.Printf("   Обработка заказа: %s\n", order.OrderUID)
 	fmt.Printf("   Трек номер: %s\n", order.TrackNumber)
-	fmt.Printf("   Клиент: %s (%s)\n", order.Delivery.Name, order.Delivery.Email)
+	fmt.Printf("   Клиент: %s (%s)\n",
+		s.masker.Mask(pii.FieldDeliveryName, order.Delivery.Name),
+		s.masker.Mask(pii.FieldDeliveryEmail, order.Delivery.Email))
 	fmt.Printf("   Сумма заказа: %d %s\n", order.Payment.Amount, order.Payment.Currency)
 	fmt.Printf("   Количество товаров: %d\n", len(order.Items))
 	fmt.Printf("   Дата создания: %s\n", order.DateCreated.Format(time.RFC3339))
 	fmt.Println("   --- Заказ сохранен в БД и кэш ---")
 
+	return order, nil
+}
+
+// выводит отклоненное сообщение с замаскированными персональными данными;
+// сообщение не в JSON не выводится, в нем нельзя найти поля для маскирования
+func (s *OrderServiceImpl) logMessage(message []byte) {
+	masked, err := s.masker.MaskJSON(message)
+	if err != nil {
+		log.Printf("Содержимое сообщения не выводится: не JSON объект, %d байт\n", len(message))
+		return
+	}
+	log.Printf("Содержимое сообщения: %s\n", masked)
+}
+
+// сохраняет заказ со всеми связанными данными в рамках транзакции
+func (s *OrderServiceImpl) saveOrder(tx *sql.Tx, order database.Order) error {
+	if err := s.repo.SaveOrder(tx, order); err != nil {
+		return fmt.Errorf("ошибка сохранения заказа: %w", err)
+	}
+
+	if err := s.repo.SaveDelivery(tx, order); err != nil {
+		return fmt.Errorf("ошибка сохранения доставки: %w", err)
+	}
+
+	if err := s.repo.SavePayment(tx, order); err != nil {
+		return fmt.Errorf("ошибка сохранения платежа: %w", err)
+	}
+
+	if err := s.repo.SaveItems(tx, order); err != nil {
+		return fmt.Errorf("ошибка сохранения товаров: %w", err)
+	}
 	return nil
 }
 
@@ -104,10 +207,20 @@ func (s *OrderServiceImpl) GetOrder(orderUID string) (database.Order, error) {
 		return order, nil
 	}
 
+	// заказ недавно не нашли в БД, повторно не ищем
+	if s.negativeCache != nil && s.negativeCache.Contains(orderUID) {
+		return database.Order{}, fmt.Errorf("заказ %s: %w", orderUID, database.ErrOrderNotFound)
+	}
+
 	// если нет в кэше, ищем в БД через репозиторий
+	start := time.Now()
 	order, err := s.repo.GetOrder(orderUID)
+	s.cache.RecordLoad(time.Since(start), err)
 	if err != nil {
-		return database.Order{}, err
+		if s.negativeCache != nil && errors.Is(err, database.ErrOrderNotFound) {
+			s.negativeCache.Add(orderUID)
+		}
+		return database.Order{}, fmt.Errorf("заказ %s: %w", orderUID, err)
 	}
 
 	// сохраняем в кэш для будущих запросов
@@ -115,52 +228,160 @@ func (s *OrderServiceImpl) GetOrder(orderUID string) (database.Order, error) {
 	return order, nil
 }
 
-// возвращает размер кэша
-func (s *OrderServiceImpl) GetCacheSize() int {
-	return s.cache.Size()
+// возвращает заказы по трек-номеру: закэшированные берутся из кэша, остальные из БД
+func (s *OrderServiceImpl) GetOrdersByTrackNumber(trackNumber string) ([]database.Order, error) {
+	orders := s.cache.GetByTrackNumber(trackNumber)
+
+	start := time.Now()
+	loaded, err := s.repo.GetOrdersByTrackNumber(trackNumber, orderUIDs(orders))
+	s.cache.RecordLoad(time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range loaded {
+		s.cache.Set(order)
+	}
+	orders = append(orders, loaded...)
+	sortNewestFirst(orders)
+	return orders, nil
 }
 
-// проверяет соединение с БД
-func (s *OrderServiceImpl) CheckDBConnection() error {
-	return s.repo.CheckConnection()
+// возвращает последние заказы покупателя: закэшированные берутся из кэша, остальные из БД
+func (s *OrderServiceImpl) GetOrdersByCustomerID(customerID string, limit int) ([]database.Order, error) {
+	orders := s.cache.GetByCustomerID(customerID)
+
+	start := time.Now()
+	loaded, err := s.repo.GetOrdersByCustomerID(customerID, orderUIDs(orders), limit)
+	s.cache.RecordLoad(time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range loaded {
+		s.cache.Set(order)
+	}
+	orders = append(orders, loaded...)
+
+	sortNewestFirst(orders)
+	if limit > 0 && len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
 }
 
-// запускает бенчмарк-тест
-func (s *OrderServiceImpl) RunBenchmark(orderUID string) (map[string]time.Duration, error) {
-	results := map[string]time.Duration{
-		"cache": 0,
-		"db":    0,
+func orderUIDs(orders []database.Order) []string {
+	uids := make([]string, len(orders))
+	for i, order := range orders {
+		uids[i] = order.OrderUID
 	}
+	return uids
+}
 
-	// тест кэша
-	start := time.Now()
-	for i := 0; i < 1000; i++ {
-		s.cache.Get(orderUID)
+// порядок как в запросах к БД: сначала новые, при равенстве по order_uid
+func sortNewestFirst(orders []database.Order) {
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].DateCreated.Equal(orders[j].DateCreated) {
+			return orders[i].DateCreated.After(orders[j].DateCreated)
+		}
+		return orders[i].OrderUID < orders[j].OrderUID
+	})
+}
+
+// ищет заказы по фильтрам; поиск всегда идет в БД, кэш для него не подходит
+func (s *OrderServiceImpl) SearchOrders(ctx context.Context, filter database.OrderSearchFilter) (database.OrderSearchResult, error) {
+	result, err := s.repo.SearchOrders(ctx, filter)
+	if err != nil {
+		return database.OrderSearchResult{}, fmt.Errorf("ошибка поиска заказов: %w", err)
 	}
-	results["cache"] = time.Since(start)
+	return result, nil
+}
 
-	// тест БД
-	start = time.Now()
-	for i := 0; i < 1000; i++ {
-		_, err := s.repo.GetOrder(orderUID)
-		if err != nil {
-			return results, fmt.Errorf("заказ не найден в БД: %s", orderUID)
+// выгружает заказы по фильтрам напрямую из БД, минуя кэш
+func (s *OrderServiceImpl) ExportOrders(ctx context.Context, filter database.OrderSearchFilter, fn func(order database.Order) error) error {
+	if err := s.repo.ExportOrders(ctx, filter, fn); err != nil {
+		return fmt.Errorf("ошибка выгрузки заказов: %w", err)
+	}
+	return nil
+}
+
+// ищет заказы по названию и бренду товаров с ранжированием по релевантности
+func (s *OrderServiceImpl) SearchOrdersText(ctx context.Context, text string, limit, offset int) ([]database.OrderTextMatch, error) {
+	matches, err := s.repo.SearchOrdersText(ctx, text, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка полнотекстового поиска: %w", err)
+	}
+	return matches, nil
+}
+
+// сбрасывает кэш по уведомлению об изменении заказа в БД
+func (s *OrderServiceImpl) HandleOrderChange(change database.OrderChange) {
+	switch change.Op {
+	case database.OrderChangeInsert:
+		if s.negativeCache != nil {
+			s.negativeCache.Remove(change.OrderUID)
 		}
+	case database.OrderChangeUpdate, database.OrderChangeDelete:
+		s.cache.Delete(change.OrderUID)
+		if s.negativeCache != nil {
+			s.negativeCache.Remove(change.OrderUID)
+		}
+		log.Printf("Заказ %s изменен в БД (%s), удален из кэша", change.OrderUID, change.Op)
+	case database.OrderChangeResync:
+		// какие заказы менялись за время разрыва, неизвестно, поэтому кэш очищается целиком,
+		// включая Redis: при перезапуске БД уведомления теряют все реплики
+		if s.negativeCache != nil {
+			s.negativeCache.Clear()
+		}
+		flushed := s.cache.Flush()
+		log.Printf("Уведомления об изменении заказов могли быть потеряны, кэш очищен: %d записей", flushed)
+	}
+}
+
+// возвращает размер кэша
+func (s *OrderServiceImpl) GetCacheSize() int {
+	return s.cache.Size()
+}
+
+// возвращает статистику кэша
+func (s *OrderServiceImpl) GetCacheStats() cache.CacheStats {
+	return s.cache.Stats()
+}
+
+// прогревает кэш, блокируется до завершения прогрева или отмены контекста
+func (s *OrderServiceImpl) WarmCache(ctx context.Context, opts cache.WarmupOptions) error {
+	warmer := cache.NewCacheWarmer(s.repo, s.cache, opts)
+
+	s.mutex.Lock()
+	s.warmup = warmer
+	s.mutex.Unlock()
+
+	return warmer.Run(ctx)
+}
+
+// возвращает состояние прогрева кэша
+func (s *OrderServiceImpl) GetWarmupProgress() cache.WarmupProgress {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.warmup == nil {
+		return cache.WarmupProgress{State: cache.WarmupPending}
 	}
-	results["db"] = time.Since(start)
+	return s.warmup.Progress()
+}
 
-	return results, nil
+// проверяет соединение с БД
+func (s *OrderServiceImpl) CheckDBConnection() error {
+	return s.repo.CheckConnection()
 }
 
 // выводит содержимое кэша
 func (s *OrderServiceImpl) PrintCacheContents() {
 	fmt.Println("Содержимое кэша:")
 	count := 0
-	s.cache.Range(func(key, value interface{}) bool {
-		if orderUID, ok := key.(string); ok {
-			fmt.Printf("  %d. %s\n", count+1, orderUID)
-			count++
-		}
+	s.cache.Range(func(orderUID string, cached cache.CachedOrder) bool {
+		fmt.Printf("  %d. %s\n", count+1, orderUID)
+		count++
 		return true
 	})
 	fmt.Printf("Всего заказов в кэше: %d\n", count)
@@ -1,22 +1,68 @@
 package service
 
 import (
+	"context"
+	"database/sql"
+	"order-service/internal/cache"
 	"order-service/internal/database"
-	"time"
 )
 
 // интерфейс для обработки заказов
 type OrderProcessor interface {
 	ProcessOrder(message []byte) error
+	SubmitOrder(message []byte) (database.Order, error)
+	ParseOrder(message []byte) (database.Order, error)
+	CheckOrder(message []byte) (database.Order, error)
 	GetOrder(orderUID string) (database.Order, error)
 	ValidateOrder(order database.Order) error
+	ImportOrders(ctx context.Context, orders []database.Order) (ImportResult, error)
+}
+
+// получает заказы, сохраненные ProcessOrder, после коммита транзакции
+type OrderListener interface {
+	OrderIngested(order database.Order)
+}
+
+// записывает события о заказе в транзакции ProcessOrder, до ее коммита;
+// ошибка откатывает заказ, и сообщение будет обработано повторно
+type OrderOutbox interface {
+	EnqueueOrder(ctx context.Context, tx *sql.Tx, order database.Order) error
+}
+
+// интерфейс поиска заказов по вторичным ключам
+type OrderLookup interface {
+	GetOrdersByTrackNumber(trackNumber string) ([]database.Order, error)
+	GetOrdersByCustomerID(customerID string, limit int) ([]database.Order, error)
+	SearchOrders(ctx context.Context, filter database.OrderSearchFilter) (database.OrderSearchResult, error)
+	ExportOrders(ctx context.Context, filter database.OrderSearchFilter, fn func(order database.Order) error) error
+	SearchOrdersText(ctx context.Context, text string, limit, offset int) ([]database.OrderTextMatch, error)
+}
+
+// интерфейс аналитики по заказам
+type OrderAnalytics interface {
+	GetSalesStats(ctx context.Context, filter database.AnalyticsFilter) ([]database.SalesPoint, error)
+	GetTopBrands(ctx context.Context, filter database.AnalyticsFilter, limit int) ([]database.TopEntry, error)
+	GetTopProducts(ctx context.Context, filter database.AnalyticsFilter, limit int) ([]database.TopEntry, error)
+}
+
+// интерфейс администрирования кэша
+type CacheAdmin interface {
+	ListCacheEntries(offset, limit int) ([]CacheEntryInfo, int)
+	EvictCacheEntry(orderUID string) bool
+	FlushCache() int
+	WarmCacheOrders(orderUIDs []string) CacheWarmResult
 }
 
 // интерфейс сервиса заказов
 type OrderService interface {
 	OrderProcessor
+	OrderLookup
+	OrderAnalytics
+	CacheAdmin
 	GetCacheSize() int
+	GetCacheStats() cache.CacheStats
+	GetWarmupProgress() cache.WarmupProgress
 	CheckDBConnection() error
-	RunBenchmark(orderUID string) (map[string]time.Duration, error)
+	RunBenchmark(ctx context.Context, orderUID string, opts BenchmarkOptions) (BenchmarkResult, error)
 	PrintCacheContents()
 }
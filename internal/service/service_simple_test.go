@@ -1,8 +1,18 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"order-service/internal/cache"
 	"order-service/internal/database"
+	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -107,6 +117,32 @@ func TestEmptyMessage(t *testing.T) {
 	}
 }
 
+// отклоненное сообщение выводится в лог без персональных данных
+func TestProcessOrderMasksLog(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	service := NewOrderService(nil, nil, nil)
+	message := `{"order_uid":"masked123","delivery":{"name":"Test Testov","phone":"+9720000000","email":"test@gmail.com","address":"Ploshad Mira 15"},"payment":{"transaction":"b563feb7-b2b8-4b6a-9f5e-2f6a4c1d8e90"}}`
+	if err := service.ProcessOrder([]byte(message)); err == nil {
+		t.Fatal("Ожидалась ошибка валидации")
+	}
+	if err := service.ProcessOrder([]byte("Test Testov test@gmail.com")); err == nil {
+		t.Fatal("Ожидалась ошибка парсинга")
+	}
+
+	logged := output.String()
+	for _, secret := range []string{"Test Testov", "test@gmail.com", "+9720000000", "Ploshad Mira", "2f6a4c1d8e90"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("В логе осталось %q:\n%s", secret, logged)
+		}
+	}
+	if !strings.Contains(logged, "masked123") || !strings.Contains(logged, "t***@gmail.com") {
+		t.Errorf("В логе нет замаскированного сообщения:\n%s", logged)
+	}
+}
+
 // тест получения заказа из кэша (упрощенный)
 func TestGetOrderFromCache(t *testing.T) {
 	// создаем простой mock кэша
@@ -131,6 +167,251 @@ func TestGetOrderFromCache(t *testing.T) {
 	}
 }
 
+// тест кэширования отсутствующих заказов
+func TestGetOrderNegativeCache(t *testing.T) {
+	repo := &SimpleRepoMock{}
+	service := &OrderServiceImpl{
+		repo:          repo,
+		cache:         &SimpleCacheMock{},
+		negativeCache: cache.NewNegativeCache(10, time.Minute),
+	}
+
+	// первый запрос доходит до БД
+	if _, err := service.GetOrder("unknown1"); !errors.Is(err, database.ErrOrderNotFound) {
+		t.Errorf("Ожидалась ошибка ErrOrderNotFound, получено: %v", err)
+	}
+
+	// повторный запрос отвечает из негативного кэша
+	if _, err := service.GetOrder("unknown1"); !errors.Is(err, database.ErrOrderNotFound) {
+		t.Errorf("Ожидалась ошибка ErrOrderNotFound, получено: %v", err)
+	}
+	if repo.getCalls != 1 {
+		t.Errorf("Ожидался 1 запрос к БД, выполнено %d", repo.getCalls)
+	}
+}
+
+// тест сброса кэша по уведомлениям из БД
+func TestHandleOrderChange(t *testing.T) {
+	orderCache := &SimpleCacheMock{}
+	negativeCache := cache.NewNegativeCache(10, time.Minute)
+	service := &OrderServiceImpl{
+		cache:         orderCache,
+		negativeCache: negativeCache,
+	}
+
+	orderCache.Set(database.Order{OrderUID: "changed1"})
+	negativeCache.Add("created1")
+
+	// новый заказ перестает считаться отсутствующим
+	service.HandleOrderChange(database.OrderChange{Op: database.OrderChangeInsert, OrderUID: "created1"})
+	if negativeCache.Contains("created1") {
+		t.Error("Созданный заказ остался в негативном кэше")
+	}
+
+	// измененный заказ удаляется из кэша
+	service.HandleOrderChange(database.OrderChange{Op: database.OrderChangeUpdate, OrderUID: "changed1"})
+	if _, found := orderCache.Get("changed1"); found {
+		t.Error("Измененный заказ остался в кэше")
+	}
+
+	// после разрыва соединения уведомления могли потеряться, кэш не должен отдавать устаревшие заказы
+	orderCache.Set(database.Order{OrderUID: "stale1"})
+	negativeCache.Add("missing1")
+	service.HandleOrderChange(database.OrderChange{Op: database.OrderChangeResync})
+	if _, found := orderCache.Get("stale1"); found {
+		t.Error("Заказ остался в кэше после потери уведомлений")
+	}
+	if negativeCache.Contains("missing1") {
+		t.Error("Негативный кэш не очищен после потери уведомлений")
+	}
+}
+
+// тест администрирования кэша
+func TestCacheAdmin(t *testing.T) {
+	orderCache := cache.NewOrderCache(10, time.Minute)
+	defer orderCache.Stop()
+	negativeCache := cache.NewNegativeCache(10, time.Minute)
+	service := &OrderServiceImpl{
+		repo:          &SimpleRepoMock{},
+		cache:         orderCache,
+		negativeCache: negativeCache,
+	}
+
+	for _, orderUID := range []string{"admin1", "admin2", "admin3"} {
+		orderCache.Set(database.Order{OrderUID: orderUID})
+	}
+
+	entries, total := service.ListCacheEntries(1, 1)
+	if total != 3 || len(entries) != 1 || entries[0].OrderUID != "admin2" {
+		t.Errorf("Ожидалась страница [admin2] из 3 записей, получено %+v из %d", entries, total)
+	}
+	if entries, _ := service.ListCacheEntries(0, 0); len(entries) != 3 || entries[0].OrderUID != "admin3" {
+		t.Errorf("Ожидались все записи, сначала admin3, получено %+v", entries)
+	}
+
+	if !service.EvictCacheEntry("admin1") || service.EvictCacheEntry("admin1") {
+		t.Error("Неверный результат удаления записи из кэша")
+	}
+
+	negativeCache.Add("missing1")
+	if flushed := service.FlushCache(); flushed != 2 || orderCache.Size() != 0 {
+		t.Errorf("Ожидалась очистка 2 записей, очищено %d, осталось %d", flushed, orderCache.Size())
+	}
+	if negativeCache.Contains("missing1") {
+		t.Error("Кэш отсутствующих заказов не очищен")
+	}
+
+	result := service.WarmCacheOrders([]string{"unknown"})
+	if len(result.NotFound) != 1 || len(result.Warmed) != 0 {
+		t.Errorf("Неверный результат прогрева: %+v", result)
+	}
+}
+
+// репозиторий для бенчмарка: безопасен для параллельных вызовов
+type benchmarkRepoMock struct {
+	database.OrderRepository
+	calls atomic.Int64
+}
+
+func (m *benchmarkRepoMock) GetOrder(orderUID string) (database.Order, error) {
+	m.calls.Add(1)
+	if orderUID != "bench1" {
+		return database.Order{}, database.ErrOrderNotFound
+	}
+	return database.Order{OrderUID: orderUID}, nil
+}
+
+// тест бенчмарка кэша и БД
+func TestRunBenchmark(t *testing.T) {
+	orderCache := cache.NewOrderCache(10, time.Minute)
+	defer orderCache.Stop()
+	repo := &benchmarkRepoMock{}
+	service := &OrderServiceImpl{repo: repo, cache: orderCache}
+
+	result, err := service.RunBenchmark(context.Background(), "bench1", BenchmarkOptions{Iterations: 200, Concurrency: 4})
+	if err != nil {
+		t.Fatalf("Ошибка бенчмарка: %v", err)
+	}
+	// один запрос загружает заказ в кэш, остальные - замер БД
+	if calls := repo.calls.Load(); calls != 201 {
+		t.Errorf("Ожидался 201 запрос к БД, выполнено %d", calls)
+	}
+	// синтетические чтения не попадают в статистику кэша
+	if stats := orderCache.Stats(); stats.Hits != 0 || stats.Misses != 1 {
+		t.Errorf("Бенчмарк изменил статистику кэша: %d попаданий, %d промахов", stats.Hits, stats.Misses)
+	}
+	for name, stats := range map[string]LatencyStats{"cache": result.Cache, "db": result.DB} {
+		if stats.Requests != 200 || stats.Throughput <= 0 {
+			t.Errorf("%s: неверная статистика %+v", name, stats)
+		}
+		if stats.P50Ms > stats.P95Ms || stats.P95Ms > stats.P99Ms || stats.P99Ms > stats.MaxMs {
+			t.Errorf("%s: перцентили не упорядочены %+v", name, stats)
+		}
+	}
+
+	if _, err := service.RunBenchmark(context.Background(), "missing", BenchmarkOptions{}); !errors.Is(err, database.ErrOrderNotFound) {
+		t.Errorf("Ожидалась ошибка ErrOrderNotFound, получено: %v", err)
+	}
+	if _, err := service.RunBenchmark(context.Background(), "bench1", BenchmarkOptions{Concurrency: MaxBenchmarkConcurrency + 1}); !errors.Is(err, database.ErrInvalidArgument) {
+		t.Errorf("Ожидалась ошибка ErrInvalidArgument, получено: %v", err)
+	}
+}
+
+// тест перцентилей по ближайшему рангу
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+	stats := latencyStats(latencies, time.Second)
+	if stats.P50Ms != 50 || stats.P95Ms != 95 || stats.P99Ms != 99 || stats.MaxMs != 100 {
+		t.Errorf("Неверные перцентили: %+v", stats)
+	}
+	if stats.Throughput != 100 {
+		t.Errorf("Ожидалось 100 запросов в секунду, получено %v", stats.Throughput)
+	}
+}
+
+// тест поиска заказов покупателя через кэш и БД
+func TestGetOrdersByCustomerID(t *testing.T) {
+	now := time.Now()
+	orderCache := cache.NewOrderCache(10, time.Minute)
+	defer orderCache.Stop()
+	repo := &SimpleRepoMock{customerOrders: []database.Order{
+		{OrderUID: "db1", CustomerID: "customer1", DateCreated: now.Add(-time.Hour)},
+		{OrderUID: "db2", CustomerID: "customer1", DateCreated: now.Add(-3 * time.Hour)},
+	}}
+	service := &OrderServiceImpl{repo: repo, cache: orderCache}
+
+	orderCache.Set(database.Order{OrderUID: "cached1", CustomerID: "customer1", DateCreated: now.Add(-2 * time.Hour)})
+
+	orders, err := service.GetOrdersByCustomerID("customer1", 2)
+	if err != nil {
+		t.Fatalf("Ошибка поиска заказов покупателя: %v", err)
+	}
+
+	// закэшированный заказ не запрашивается из БД повторно
+	if len(repo.excludedUIDs) != 1 || repo.excludedUIDs[0] != "cached1" {
+		t.Errorf("Ожидалось исключение cached1 из запроса к БД, получено %v", repo.excludedUIDs)
+	}
+	if len(orders) != 2 || orders[0].OrderUID != "db1" || orders[1].OrderUID != "cached1" {
+		t.Errorf("Неверный порядок заказов покупателя: %+v", orders)
+	}
+
+	// загруженные из БД заказы попадают в индекс кэша
+	if cached := orderCache.GetByCustomerID("customer1"); len(cached) != 3 {
+		t.Errorf("Ожидалось 3 заказа покупателя в кэше, получено %d", len(cached))
+	}
+}
+
+// заказы трека, закэшированные лишь частично, дополняются из БД
+func TestGetOrdersByTrackNumber(t *testing.T) {
+	now := time.Now()
+	orderCache := cache.NewOrderCache(10, time.Minute)
+	defer orderCache.Stop()
+	repo := &SimpleRepoMock{trackOrders: []database.Order{
+		{OrderUID: "db1", TrackNumber: "TRACK", DateCreated: now.Add(-2 * time.Hour)},
+	}}
+	service := &OrderServiceImpl{repo: repo, cache: orderCache}
+
+	orderCache.Set(database.Order{OrderUID: "cached1", TrackNumber: "TRACK", DateCreated: now.Add(-time.Hour)})
+
+	orders, err := service.GetOrdersByTrackNumber("TRACK")
+	if err != nil {
+		t.Fatalf("Ошибка поиска заказов по треку: %v", err)
+	}
+	if len(repo.excludedUIDs) != 1 || repo.excludedUIDs[0] != "cached1" {
+		t.Errorf("Ожидалось исключение cached1 из запроса к БД, получено %v", repo.excludedUIDs)
+	}
+	if len(orders) != 2 || orders[0].OrderUID != "cached1" || orders[1].OrderUID != "db1" {
+		t.Errorf("Ожидались заказы cached1 и db1, получено %+v", orders)
+	}
+}
+
+// простой mock репозитория
+type SimpleRepoMock struct {
+	database.OrderRepository
+	getCalls       int
+	customerOrders []database.Order
+	trackOrders    []database.Order
+	excludedUIDs   []string
+}
+
+func (m *SimpleRepoMock) GetOrdersByTrackNumber(trackNumber string, excludeUIDs []string) ([]database.Order, error) {
+	m.excludedUIDs = excludeUIDs
+	return m.trackOrders, nil
+}
+
+func (m *SimpleRepoMock) GetOrdersByCustomerID(customerID string, excludeUIDs []string, limit int) ([]database.Order, error) {
+	m.excludedUIDs = excludeUIDs
+	return m.customerOrders, nil
+}
+
+func (m *SimpleRepoMock) GetOrder(orderUID string) (database.Order, error) {
+	m.getCalls++
+	return database.Order{}, fmt.Errorf("%w: %w", database.ErrOrderNotFound, sql.ErrNoRows)
+}
+
 // простой mock для кэша
 type SimpleCacheMock struct {
 	storage map[string]database.Order
@@ -141,6 +422,10 @@ func (m *SimpleCacheMock) Get(orderUID string) (database.Order, bool) {
 	return order, exists
 }
 
+func (m *SimpleCacheMock) Peek(orderUID string) (database.Order, bool) {
+	return m.Get(orderUID)
+}
+
 func (m *SimpleCacheMock) Set(order database.Order) {
 	if m.storage == nil {
 		m.storage = make(map[string]database.Order)
@@ -148,7 +433,21 @@ func (m *SimpleCacheMock) Set(order database.Order) {
 	m.storage[order.OrderUID] = order
 }
 
-func (m *SimpleCacheMock) Delete(orderUID string) {}
+func (m *SimpleCacheMock) Delete(orderUID string) bool {
+	_, exists := m.storage[orderUID]
+	delete(m.storage, orderUID)
+	return exists
+}
+
+func (m *SimpleCacheMock) Flush() int {
+	count := len(m.storage)
+	m.storage = nil
+	return count
+}
+
+func (m *SimpleCacheMock) Entries(offset, limit int) ([]cache.CachedOrder, int) {
+	return nil, len(m.storage)
+}
 
 func (m *SimpleCacheMock) Size() int {
 	if m.storage == nil {
@@ -161,10 +460,36 @@ func (m *SimpleCacheMock) Cleanup(ttl time.Duration) {}
 
 func (m *SimpleCacheMock) Stop() {}
 
-func (m *SimpleCacheMock) Range(f func(key, value interface{}) bool) {
+func (m *SimpleCacheMock) GetByTrackNumber(trackNumber string) []database.Order {
+	var orders []database.Order
+	for _, order := range m.storage {
+		if order.TrackNumber == trackNumber {
+			orders = append(orders, order)
+		}
+	}
+	return orders
+}
+
+func (m *SimpleCacheMock) GetByCustomerID(customerID string) []database.Order {
+	var orders []database.Order
+	for _, order := range m.storage {
+		if order.CustomerID == customerID {
+			orders = append(orders, order)
+		}
+	}
+	return orders
+}
+
+func (m *SimpleCacheMock) Stats() cache.CacheStats {
+	return cache.CacheStats{Size: m.Size()}
+}
+
+func (m *SimpleCacheMock) RecordLoad(duration time.Duration, err error) {}
+
+func (m *SimpleCacheMock) Range(f func(orderUID string, cached cache.CachedOrder) bool) {
 	for k, v := range m.storage {
 		//проверяем нужно ли продолжать
-		if !f(k, v) {
+		if !f(k, cache.CachedOrder{Order: v}) {
 			break
 		}
 	}
@@ -239,4 +564,37 @@ func TestValidatorService(t *testing.T) {
 	} else {
 		t.Logf("Ожидаемая ошибка валидации: %v", err)
 	}
+
+	// суммы платежа не сходятся с товарами
+	mismatched := validOrder
+	mismatched.Payment.GoodsTotal = 300
+	mismatched.Payment.Amount = 1900
+
+	// суммы проверяются только в строгом режиме, заказы из Kafka и импорта не отклоняются
+	if err := validator.ValidateOrder(mismatched); err != nil {
+		t.Errorf("Несходящиеся суммы не должны проверяться без строгого режима: %v", err)
+	}
+	if err := validator.ValidateOrderStrict(validOrder); err != nil {
+		t.Errorf("Валидный заказ не прошел строгую проверку: %v", err)
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(validator.ValidateOrderStrict(mismatched), &validationErr) {
+		t.Fatal("Ожидалась ValidationError для несходящихся сумм")
+	}
+	fields := make(map[string]string)
+	for _, field := range validationErr.Fields {
+		fields[field.Field] = field.Rule
+	}
+	if fields["payment.goods_total"] != "goods_total" || fields["payment.amount"] != "amount" {
+		t.Errorf("Ожидались ошибки goods_total и amount, получено %+v", validationErr.Fields)
+	}
+
+	// путь поля товара включает индекс
+	invalidItem := validOrder
+	invalidItem.Items = []database.Item{validOrder.Items[0]}
+	invalidItem.Items[0].Brand = ""
+	if !errors.As(validator.ValidateOrder(invalidItem), &validationErr) || validationErr.Fields[0].Field != "items[0].brand" {
+		t.Errorf("Ожидалась ошибка поля items[0].brand, получено %v", validationErr)
+	}
 }
@@ -36,53 +36,134 @@ func NewValidatorService() *ValidatorService {
 	return &ValidatorService{validate: v}
 }
 
+// ошибка одного поля заказа
+type FieldError struct {
+	// путь к полю в JSON, например delivery.phone или items[0].price
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ошибка валидации заказа со списком всех нарушений
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// проверяет теги validate; возвращает *ValidationError
 func (vs *ValidatorService) ValidateOrder(order database.Order) error {
-	err := vs.validate.Struct(order)
-	if err != nil {
-		return vs.formatValidationError(err)
+	return vs.validateOrder(order, false)
+}
+
+// проверяет теги validate и сходимость сумм платежа; используется для заказов,
+// принятых по HTTP, и проверки без сохранения, но не для Kafka и импорта
+func (vs *ValidatorService) ValidateOrderStrict(order database.Order) error {
+	return vs.validateOrder(order, true)
+}
+
+func (vs *ValidatorService) validateOrder(order database.Order, businessRules bool) error {
+	var fields []FieldError
+
+	if err := vs.validate.Struct(order); err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			return err
+		}
+		fields = vs.formatValidationError(validationErrors)
+	}
+	if businessRules {
+		fields = append(fields, businessRuleErrors(order)...)
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
 	}
 	return nil
 }
 
-func (vs *ValidatorService) formatValidationError(err error) error {
-	if validationErrors, ok := err.(validator.ValidationErrors); ok {
-		var errorMessages []string
-
-		for _, fieldError := range validationErrors {
-			var message string
-
-			switch fieldError.Tag() {
-			case "required":
-				message = fmt.Sprintf("поле '%s' обязательно для заполнения", fieldError.Field())
-			case "min":
-				message = fmt.Sprintf("поле '%s' должно быть не менее %s", fieldError.Field(), fieldError.Param())
-			case "max":
-				message = fmt.Sprintf("поле '%s' должно быть не более %s", fieldError.Field(), fieldError.Param())
-			case "email":
-				message = fmt.Sprintf("поле '%s' должно быть валидным email адресом", fieldError.Field())
-			case "e164":
-				message = fmt.Sprintf("поле '%s' должно быть в формате E.164 (например: +79161234567)", fieldError.Field())
-			case "uuid":
-				message = fmt.Sprintf("поле '%s' должно быть в формате UUID", fieldError.Field())
-			case "alpha":
-				message = fmt.Sprintf("поле '%s' должно содержать только буквы", fieldError.Field())
-			case "alphanum":
-				message = fmt.Sprintf("поле '%s' должно содержать только буквы и цифры", fieldError.Field())
-			case "numeric":
-				message = fmt.Sprintf("поле '%s' должно содержать только цифры", fieldError.Field())
-			case "uppercase":
-				message = fmt.Sprintf("поле '%s' должно быть в верхнем регистре", fieldError.Field())
-			default:
-				message = fmt.Sprintf("поле '%s' невалидно: %s", fieldError.Field(), fieldError.Tag())
-			}
-
-			errorMessages = append(errorMessages, message)
+// суммы платежа должны сходиться с товарами, иначе заказ испорчен на стороне продюсера
+func businessRuleErrors(order database.Order) []FieldError {
+	var fields []FieldError
+
+	goodsTotal := 0
+	for _, item := range order.Items {
+		goodsTotal += item.TotalPrice
+	}
+	if order.Payment.GoodsTotal != goodsTotal {
+		fields = append(fields, FieldError{
+			Field: "payment.goods_total",
+			Rule:  "goods_total",
+			Message: fmt.Sprintf("поле 'goods_total' (%d) должно равняться сумме total_price товаров (%d)",
+				order.Payment.GoodsTotal, goodsTotal),
+		})
+	}
+
+	expectedAmount := order.Payment.GoodsTotal + order.Payment.DeliveryCost + order.Payment.CustomFee
+	if order.Payment.Amount != expectedAmount {
+		fields = append(fields, FieldError{
+			Field: "payment.amount",
+			Rule:  "amount",
+			Message: fmt.Sprintf("поле 'amount' (%d) должно равняться goods_total + delivery_cost + custom_fee (%d)",
+				order.Payment.Amount, expectedAmount),
+		})
+	}
+	return fields
+}
+
+func (vs *ValidatorService) formatValidationError(validationErrors validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(validationErrors))
+
+	for _, fieldError := range validationErrors {
+		var message string
+
+		switch fieldError.Tag() {
+		case "required":
+			message = fmt.Sprintf("поле '%s' обязательно для заполнения", fieldError.Field())
+		case "min":
+			message = fmt.Sprintf("поле '%s' должно быть не менее %s", fieldError.Field(), fieldError.Param())
+		case "max":
+			message = fmt.Sprintf("поле '%s' должно быть не более %s", fieldError.Field(), fieldError.Param())
+		case "email":
+			message = fmt.Sprintf("поле '%s' должно быть валидным email адресом", fieldError.Field())
+		case "e164":
+			message = fmt.Sprintf("поле '%s' должно быть в формате E.164 (например: +79161234567)", fieldError.Field())
+		case "uuid":
+			message = fmt.Sprintf("поле '%s' должно быть в формате UUID", fieldError.Field())
+		case "alpha":
+			message = fmt.Sprintf("поле '%s' должно содержать только буквы", fieldError.Field())
+		case "alphanum":
+			message = fmt.Sprintf("поле '%s' должно содержать только буквы и цифры", fieldError.Field())
+		case "numeric":
+			message = fmt.Sprintf("поле '%s' должно содержать только цифры", fieldError.Field())
+		case "uppercase":
+			message = fmt.Sprintf("поле '%s' должно быть в верхнем регистре", fieldError.Field())
+		default:
+			message = fmt.Sprintf("поле '%s' невалидно: %s", fieldError.Field(), fieldError.Tag())
 		}
 
-		return fmt.Errorf("%s", strings.Join(errorMessages, "; "))
+		fields = append(fields, FieldError{
+			Field:   fieldPath(fieldError.Namespace()),
+			Rule:    fieldError.Tag(),
+			Message: message,
+		})
 	}
 
-	return err
+	return fields
+}
+
+// убирает имя корневой структуры из пути поля: Order.delivery.phone -> delivery.phone
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
 }
 
 func (vs *ValidatorService) ValidateStruct(s interface{}) error {
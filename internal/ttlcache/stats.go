@@ -0,0 +1,129 @@
+package ttlcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// причины удаления записи из кэша
+type EvictionReason int
+
+const (
+	// вытеснена при достижении MaxSize
+	EvictionCapacity EvictionReason = iota
+	// истек срок жизни
+	EvictionExpired
+	// удалена вызовом Delete
+	EvictionDeleted
+	// заменена новым значением через Set
+	EvictionReplaced
+)
+
+// статистика вытеснений по причинам
+type EvictionStats struct {
+	Capacity int64 `json:"capacity"`
+	TTL      int64 `json:"ttl"`
+	Manual   int64 `json:"manual"`
+}
+
+// статистика работы кэша
+type Stats struct {
+	Size        int           `json:"size"`
+	Hits        int64         `json:"hits"`
+	Misses      int64         `json:"misses"`
+	HitRatio    float64       `json:"hit_ratio"`
+	Expirations int64         `json:"expirations"`
+	Evictions   EvictionStats `json:"evictions"`
+	Loads       int64         `json:"loads"`
+	LoadErrors  int64         `json:"load_errors"`
+	LoadTimeAvg time.Duration `json:"load_time_avg_ns"`
+	LoadTimeMax time.Duration `json:"load_time_max_ns"`
+	Refreshes   int64         `json:"refreshes"`
+}
+
+// счетчики статистики, безопасны для конкурентного доступа;
+// используются и реализациями кэша вне пакета
+type Counters struct {
+	hits              atomic.Int64
+	misses            atomic.Int64
+	expirations       atomic.Int64
+	evictionsCapacity atomic.Int64
+	evictionsTTL      atomic.Int64
+	evictionsManual   atomic.Int64
+	loads             atomic.Int64
+	loadErrors        atomic.Int64
+	loadTimeTotal     atomic.Int64
+	loadTimeMax       atomic.Int64
+	refreshes         atomic.Int64
+}
+
+func (c *Counters) RecordHit() {
+	c.hits.Add(1)
+}
+
+func (c *Counters) RecordMiss() {
+	c.misses.Add(1)
+}
+
+func (c *Counters) RecordExpiration() {
+	c.expirations.Add(1)
+}
+
+func (c *Counters) RecordRefresh() {
+	c.refreshes.Add(1)
+}
+
+// замена значения вытеснением не считается
+func (c *Counters) RecordEviction(reason EvictionReason) {
+	switch reason {
+	case EvictionCapacity:
+		c.evictionsCapacity.Add(1)
+	case EvictionExpired:
+		c.evictionsTTL.Add(1)
+	case EvictionDeleted:
+		c.evictionsManual.Add(1)
+	}
+}
+
+func (c *Counters) RecordLoad(duration time.Duration, err error) {
+	c.loads.Add(1)
+	if err != nil {
+		c.loadErrors.Add(1)
+	}
+	c.loadTimeTotal.Add(int64(duration))
+
+	for {
+		current := c.loadTimeMax.Load()
+		if int64(duration) <= current || c.loadTimeMax.CompareAndSwap(current, int64(duration)) {
+			return
+		}
+	}
+}
+
+// возвращает снимок счетчиков
+func (c *Counters) Snapshot(size int) Stats {
+	stats := Stats{
+		Size:        size,
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Expirations: c.expirations.Load(),
+		Evictions: EvictionStats{
+			Capacity: c.evictionsCapacity.Load(),
+			TTL:      c.evictionsTTL.Load(),
+			Manual:   c.evictionsManual.Load(),
+		},
+		Loads:       c.loads.Load(),
+		LoadErrors:  c.loadErrors.Load(),
+		LoadTimeMax: time.Duration(c.loadTimeMax.Load()),
+		Refreshes:   c.refreshes.Load(),
+	}
+
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	if stats.Loads > 0 {
+		stats.LoadTimeAvg = time.Duration(c.loadTimeTotal.Load() / stats.Loads)
+	}
+
+	return stats
+}
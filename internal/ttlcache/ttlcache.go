@@ -0,0 +1,372 @@
+package ttlcache
+
+import (
+	"container/list"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// источник текущего времени, подменяется в тестах
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// параметры кэша
+type Options[K comparable, V any] struct {
+	// при MaxSize <= 0 размер не ограничен, иначе вытесняется самая старая запись
+	MaxSize int
+	// при TTL <= 0 записи не истекают
+	TTL time.Duration
+	// чтение записи продлевает ее жизнь на TTL
+	SlidingExpiration bool
+	// к TTL каждой записи добавляется случайная величина из [0, TTLJitter)
+	TTLJitter time.Duration
+	// запись, которой при чтении осталось жить меньше RefreshAhead, перезагружается через Loader в фоне
+	RefreshAhead time.Duration
+	Loader       func(key K) (V, error)
+	Clock        Clock
+	// период фоновой очистки истекших записей, 0 - без фоновой очистки
+	CleanupInterval time.Duration
+	// колбэки вызываются под блокировкой кэша и не должны обращаться к нему
+	OnSet   func(key K, value V)
+	OnEvict func(key K, value V, reason EvictionReason)
+}
+
+// запись кэша
+type Entry[K comparable, V any] struct {
+	Key       K
+	Value     V
+	CreatedAt time.Time
+	// нулевое значение - запись не истекает
+	ExpiresAt time.Time
+}
+
+type item[K comparable, V any] struct {
+	key        K
+	value      V
+	createdAt  time.Time
+	expiresAt  atomic.Int64
+	refreshing atomic.Bool
+	element    *list.Element
+}
+
+func (it *item[K, V]) expired(now time.Time) bool {
+	expiresAt := it.expiresAt.Load()
+	return expiresAt != 0 && now.UnixNano() > expiresAt
+}
+
+func (it *item[K, V]) entry() Entry[K, V] {
+	entry := Entry[K, V]{Key: it.key, Value: it.value, CreatedAt: it.createdAt}
+	if expiresAt := it.expiresAt.Load(); expiresAt != 0 {
+		entry.ExpiresAt = time.Unix(0, expiresAt)
+	}
+	return entry
+}
+
+// потокобезопасный кэш с ограничением размера и сроком жизни записей
+type Cache[K comparable, V any] struct {
+	opts     Options[K, V]
+	mutex    sync.RWMutex
+	items    map[K]*item[K, V]
+	order    *list.List
+	stats    Counters
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// создает кэш
+func New[K comparable, V any](opts Options[K, V]) *Cache[K, V] {
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+
+	c := &Cache[K, V]{
+		opts:     opts,
+		items:    make(map[K]*item[K, V]),
+		order:    list.New(),
+		stopChan: make(chan struct{}),
+	}
+
+	if opts.CleanupInterval > 0 {
+		go c.startCleanupWorker()
+	}
+
+	return c
+}
+
+// возвращает значение, если запись есть и не истекла
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	var zero V
+
+	c.mutex.RLock()
+	it, ok := c.items[key]
+	c.mutex.RUnlock()
+
+	if !ok {
+		c.stats.RecordMiss()
+		return zero, false
+	}
+
+	now := c.opts.Clock.Now()
+	if it.expired(now) {
+		c.stats.RecordExpiration()
+		c.stats.RecordMiss()
+		c.mutex.Lock()
+		if c.items[key] == it {
+			c.removeLocked(it, EvictionExpired)
+		}
+		c.mutex.Unlock()
+		return zero, false
+	}
+
+	if expiresAt := it.expiresAt.Load(); expiresAt != 0 {
+		if c.opts.SlidingExpiration {
+			expiresAt = now.Add(c.entryTTL()).UnixNano()
+			it.expiresAt.Store(expiresAt)
+		}
+		if c.opts.RefreshAhead > 0 && c.opts.Loader != nil && expiresAt-now.UnixNano() < int64(c.opts.RefreshAhead) {
+			c.refresh(it)
+		}
+	}
+
+	c.stats.RecordHit()
+	return it.value, true
+}
+
+// возвращает запись без учета в статистике и без продления срока жизни
+func (c *Cache[K, V]) Peek(key K) (Entry[K, V], bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	it, ok := c.items[key]
+	if !ok || it.expired(c.opts.Clock.Now()) {
+		return Entry[K, V]{}, false
+	}
+	return it.entry(), true
+}
+
+// добавляет или заменяет запись
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.setLocked(key, value)
+}
+
+// удаляет запись, возвращает false если ее не было
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	it, ok := c.items[key]
+	if ok {
+		c.removeLocked(it, EvictionDeleted)
+	}
+	return ok
+}
+
+// удаляет все записи, возвращает количество удаленных
+func (c *Cache[K, V]) Clear() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	removed := len(c.items)
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		c.removeLocked(element.Value.(*item[K, V]), EvictionDeleted)
+		element = next
+	}
+	return removed
+}
+
+// возвращает количество записей, включая еще не удаленные истекшие
+func (c *Cache[K, V]) Len() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.items)
+}
+
+// итерируется по неистекшим записям от старых к новым;
+// f вызывается вне блокировки и может изменять кэш
+func (c *Cache[K, V]) Range(f func(entry Entry[K, V]) bool) {
+	now := c.opts.Clock.Now()
+
+	c.mutex.RLock()
+	entries := make([]Entry[K, V], 0, len(c.items))
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		it := element.Value.(*item[K, V])
+		if !it.expired(now) {
+			entries = append(entries, it.entry())
+		}
+	}
+	c.mutex.RUnlock()
+
+	for _, entry := range entries {
+		if !f(entry) {
+			return
+		}
+	}
+}
+
+// возвращает страницу неистекших записей от новых к старым и их общее количество;
+// копируются только записи страницы, limit <= 0 - до конца
+func (c *Cache[K, V]) Page(offset, limit int) ([]Entry[K, V], int) {
+	now := c.opts.Clock.Now()
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entries := []Entry[K, V]{}
+	total := 0
+	for element := c.order.Back(); element != nil; element = element.Prev() {
+		it := element.Value.(*item[K, V])
+		if it.expired(now) {
+			continue
+		}
+		if total >= offset && (limit <= 0 || len(entries) < limit) {
+			entries = append(entries, it.entry())
+		}
+		total++
+	}
+	return entries, total
+}
+
+// удаляет истекшие записи, возвращает количество удаленных
+func (c *Cache[K, V]) RemoveExpired() int {
+	return c.removeIf(func(it *item[K, V], now time.Time) bool {
+		return it.expired(now)
+	})
+}
+
+// удаляет истекшие записи и записи, добавленные раньше чем age назад
+func (c *Cache[K, V]) RemoveOlderThan(age time.Duration) int {
+	return c.removeIf(func(it *item[K, V], now time.Time) bool {
+		return it.expired(now) || now.Sub(it.createdAt) > age
+	})
+}
+
+// возвращает статистику кэша
+func (c *Cache[K, V]) Stats() Stats {
+	return c.stats.Snapshot(c.Len())
+}
+
+// учитывает внешнюю загрузку значения, например промах кэша с походом в БД
+func (c *Cache[K, V]) RecordLoad(duration time.Duration, err error) {
+	c.stats.RecordLoad(duration, err)
+}
+
+// останавливает фоновую очистку
+func (c *Cache[K, V]) Close() {
+	c.stopOnce.Do(func() {
+		close(c.stopChan)
+	})
+}
+
+// Вспомогательные методы
+
+// TTL записи с учетом случайного разброса
+func (c *Cache[K, V]) entryTTL() time.Duration {
+	if c.opts.TTLJitter <= 0 {
+		return c.opts.TTL
+	}
+	return c.opts.TTL + time.Duration(rand.Int64N(int64(c.opts.TTLJitter)))
+}
+
+func (c *Cache[K, V]) setLocked(key K, value V) {
+	now := c.opts.Clock.Now()
+	it := &item[K, V]{key: key, value: value, createdAt: now}
+	if c.opts.TTL > 0 {
+		it.expiresAt.Store(now.Add(c.entryTTL()).UnixNano())
+	}
+
+	if previous, ok := c.items[key]; ok {
+		c.removeLocked(previous, EvictionReplaced)
+	} else if c.opts.MaxSize > 0 && len(c.items) >= c.opts.MaxSize {
+		if oldest := c.order.Front(); oldest != nil {
+			c.removeLocked(oldest.Value.(*item[K, V]), EvictionCapacity)
+		}
+	}
+
+	it.element = c.order.PushBack(it)
+	c.items[key] = it
+
+	if c.opts.OnSet != nil {
+		c.opts.OnSet(key, value)
+	}
+}
+
+func (c *Cache[K, V]) removeLocked(it *item[K, V], reason EvictionReason) {
+	delete(c.items, it.key)
+	c.order.Remove(it.element)
+	c.stats.RecordEviction(reason)
+
+	if c.opts.OnEvict != nil {
+		c.opts.OnEvict(it.key, it.value, reason)
+	}
+}
+
+func (c *Cache[K, V]) removeIf(remove func(it *item[K, V], now time.Time) bool) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.opts.Clock.Now()
+	removed := 0
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		if it := element.Value.(*item[K, V]); remove(it, now) {
+			c.removeLocked(it, EvictionExpired)
+			removed++
+		}
+		element = next
+	}
+	return removed
+}
+
+// перезагружает запись в фоне, одновременно не более одной загрузки на запись
+func (c *Cache[K, V]) refresh(it *item[K, V]) {
+	if !it.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		start := time.Now()
+		value, err := c.opts.Loader(it.key)
+		c.stats.RecordLoad(time.Since(start), err)
+		if err != nil {
+			log.Printf("Ошибка фонового обновления записи кэша %v: %v", it.key, err)
+			it.refreshing.Store(false)
+			return
+		}
+
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		// запись могли заменить или удалить, пока шла загрузка
+		if c.items[it.key] == it {
+			c.stats.RecordRefresh()
+			c.setLocked(it.key, value)
+		}
+	}()
+}
+
+func (c *Cache[K, V]) startCleanupWorker() {
+	ticker := time.NewTicker(c.opts.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.RemoveExpired()
+		case <-c.stopChan:
+			return
+		}
+	}
+}
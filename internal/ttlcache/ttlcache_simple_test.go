@@ -0,0 +1,139 @@
+package ttlcache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestCacheBasicOperations(t *testing.T) {
+	cache := New(Options[string, int]{MaxSize: 2})
+	defer cache.Close()
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+
+	if value, found := cache.Get("a"); !found || value != 1 {
+		t.Errorf("Ожидалось значение 1, получено %d (найдено: %v)", value, found)
+	}
+
+	// при переполнении вытесняется самая старая запись
+	cache.Set("c", 3)
+	if _, found := cache.Get("a"); found {
+		t.Error("Самая старая запись должна быть вытеснена")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Ожидался размер 2, получен %d", cache.Len())
+	}
+
+	if !cache.Delete("b") {
+		t.Error("Delete должен вернуть true для существующей записи")
+	}
+	if cache.Delete("b") {
+		t.Error("Delete должен вернуть false для отсутствующей записи")
+	}
+}
+
+func TestCacheExpiration(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	cache := New(Options[string, string]{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+
+	cache.Set("key", "value")
+	clock.Advance(30 * time.Second)
+	if _, found := cache.Get("key"); !found {
+		t.Error("Запись не должна истечь раньше TTL")
+	}
+
+	clock.Advance(time.Minute)
+	if _, found := cache.Get("key"); found {
+		t.Error("Запись должна истечь после TTL")
+	}
+
+	cache.Set("other", "value")
+	clock.Advance(2 * time.Minute)
+	if removed := cache.RemoveExpired(); removed != 1 {
+		t.Errorf("Ожидалось удаление 1 записи, удалено %d", removed)
+	}
+
+	stats := cache.Stats()
+	if stats.Expirations != 1 || stats.Evictions.TTL != 2 {
+		t.Errorf("Неверная статистика истечений: %+v", stats)
+	}
+}
+
+func TestCacheEvictCallback(t *testing.T) {
+	reasons := make(map[string]EvictionReason)
+	cache := New(Options[string, int]{
+		MaxSize: 1,
+		OnEvict: func(key string, value int, reason EvictionReason) {
+			reasons[key] = reason
+		},
+	})
+	defer cache.Close()
+
+	cache.Set("a", 1)
+	cache.Set("a", 2)
+	if reasons["a"] != EvictionReplaced {
+		t.Errorf("Ожидалась причина EvictionReplaced, получена %d", reasons["a"])
+	}
+
+	cache.Set("b", 3)
+	if reasons["a"] != EvictionCapacity {
+		t.Errorf("Ожидалась причина EvictionCapacity, получена %d", reasons["a"])
+	}
+
+	cache.Delete("b")
+	if reasons["b"] != EvictionDeleted {
+		t.Errorf("Ожидалась причина EvictionDeleted, получена %d", reasons["b"])
+	}
+
+	// замена значения не считается вытеснением
+	stats := cache.Stats()
+	if stats.Evictions.Capacity != 1 || stats.Evictions.Manual != 1 {
+		t.Errorf("Неверная статистика вытеснений: %+v", stats.Evictions)
+	}
+}
+
+func TestCacheRange(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	cache := New(Options[int, string]{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+
+	cache.Set(1, "old")
+	clock.Advance(45 * time.Second)
+	cache.Set(2, "new")
+	clock.Advance(30 * time.Second)
+
+	// истекшие записи не попадают в обход, а f может изменять кэш
+	var keys []int
+	cache.Range(func(entry Entry[int, string]) bool {
+		keys = append(keys, entry.Key)
+		cache.Delete(entry.Key)
+		return true
+	})
+
+	if len(keys) != 1 || keys[0] != 2 {
+		t.Errorf("Ожидалась только запись 2, получено %v", keys)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Истекшая запись должна остаться до очистки, размер %d", cache.Len())
+	}
+}
@@ -0,0 +1,284 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"order-service/internal/database"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// событие о новом заказе
+const EventOrderCreated = "order.created"
+
+// сколько байт ответа получателя сохраняется в журнал
+const maxErrorBodySize = 512
+
+// параметры доставки уведомлений
+type Options struct {
+	// параллельных HTTP запросов
+	Workers int
+	// после стольких неудачных попыток доставка помечается failed
+	MaxAttempts int
+	// таймаут одного запроса
+	Timeout time.Duration
+	// как часто проверять очередь, если новых заказов нет
+	PollInterval time.Duration
+	// задержка перед второй попыткой, дальше удваивается до RetryMax
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// доставок, захватываемых за один запрос к БД
+	BatchSize int
+	// клиент для тестов; по умолчанию http.Client с Timeout
+	Client *http.Client
+}
+
+// тело уведомления
+type payload struct {
+	Event     string         `json:"event"`
+	CreatedAt time.Time      `json:"created_at"`
+	Order     database.Order `json:"order"`
+}
+
+// доставляет уведомления из очереди в БД; очередь переживает рестарт,
+// а захват с SKIP LOCKED позволяет запускать несколько реплик
+type Dispatcher struct {
+	repo database.WebhookRepository
+	opts Options
+	wake chan struct{}
+}
+
+// создает диспетчер уведомлений
+func NewDispatcher(repo database.WebhookRepository, opts Options) Manager {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.RetryBase <= 0 {
+		opts.RetryBase = 10 * time.Second
+	}
+	if opts.RetryMax < opts.RetryBase {
+		opts.RetryMax = opts.RetryBase
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = opts.Workers * 4
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: opts.Timeout}
+	}
+	return &Dispatcher{repo: repo, opts: opts, wake: make(chan struct{}, 1)}
+}
+
+// ставит уведомления о заказе в очередь в транзакции его сохранения (outbox):
+// заказ и его доставки сохраняются или откатываются вместе
+func (d *Dispatcher) EnqueueOrder(ctx context.Context, tx *sql.Tx, order database.Order) error {
+	body, err := json.Marshal(payload{Event: EventOrderCreated, CreatedAt: time.Now().UTC(), Order: order})
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации уведомления: %w", err)
+	}
+	if _, err := d.repo.EnqueueOrderDeliveries(ctx, tx, order, EventOrderCreated, body); err != nil {
+		return err
+	}
+	return nil
+}
+
+// вызывается после коммита заказа; доставки уже в очереди, остается разбудить отправку
+func (d *Dispatcher) OrderIngested(order database.Order) {
+	d.notify()
+}
+
+// будит цикл отправки, не блокируясь, если он уже разбужен
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// сохраняет подписку; без секрета генерирует случайный, он возвращается только здесь
+func (d *Dispatcher) CreateSubscription(ctx context.Context, sub database.WebhookSubscription) (database.WebhookSubscription, error) {
+	target, err := url.Parse(sub.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return database.WebhookSubscription{}, fmt.Errorf("%w: url должен быть абсолютным http(s) адресом", database.ErrInvalidArgument)
+	}
+
+	if sub.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return database.WebhookSubscription{}, fmt.Errorf("ошибка генерации секрета: %v", err)
+		}
+		sub.Secret = hex.EncodeToString(secret)
+	}
+	sub.Active = true
+
+	return d.repo.CreateSubscription(ctx, sub)
+}
+
+func (d *Dispatcher) ListSubscriptions(ctx context.Context) ([]database.WebhookSubscription, error) {
+	return d.repo.ListSubscriptions(ctx)
+}
+
+func (d *Dispatcher) DeleteSubscription(ctx context.Context, id int64) error {
+	return d.repo.DeleteSubscription(ctx, id)
+}
+
+func (d *Dispatcher) ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]database.WebhookDelivery, error) {
+	return d.repo.ListDeliveries(ctx, subscriptionID, limit)
+}
+
+// возвращает доставку в очередь, в том числе уже доставленную или failed
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryID int64) error {
+	if err := d.repo.RetryDelivery(ctx, deliveryID); err != nil {
+		return err
+	}
+	d.notify()
+	return nil
+}
+
+// отправляет доставки, пока не отменен контекст
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Printf("Доставка вебхуков запущена: воркеров %d, попыток %d", d.opts.Workers, d.opts.MaxAttempts)
+
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Доставка вебхуков остановлена")
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// отправляет все доставки, время которых пришло; возвращает число отправленных
+func (d *Dispatcher) dispatchDue(ctx context.Context) int {
+	// пока запрос в полете, доставка не должна достаться другой реплике
+	lease := 2*d.opts.Timeout + time.Minute
+	total := 0
+
+	for ctx.Err() == nil {
+		deliveries, err := d.repo.ClaimDueDeliveries(ctx, d.opts.BatchSize, lease)
+		if err != nil {
+			log.Printf("Ошибка получения очереди вебхуков: %v", err)
+			return total
+		}
+		if len(deliveries) == 0 {
+			return total
+		}
+
+		semaphore := make(chan struct{}, d.opts.Workers)
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			semaphore <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				d.deliver(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+
+		total += len(deliveries)
+		if len(deliveries) < d.opts.BatchSize {
+			return total
+		}
+	}
+	return total
+}
+
+// выполняет одну попытку доставки и сохраняет ее результат
+func (d *Dispatcher) deliver(ctx context.Context, delivery database.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.LastStatusCode = 0
+	delivery.LastError = ""
+
+	statusCode, err := d.send(ctx, delivery)
+	delivery.LastStatusCode = statusCode
+
+	var retryIn time.Duration
+	switch {
+	case err == nil:
+		delivery.Status = database.WebhookDelivered
+	case delivery.Attempts >= d.opts.MaxAttempts:
+		delivery.Status = database.WebhookFailed
+		delivery.LastError = err.Error()
+		log.Printf("Вебхук %d для заказа %s не доставлен за %d попыток: %v",
+			delivery.ID, delivery.OrderUID, delivery.Attempts, err)
+	default:
+		delivery.Status = database.WebhookPending
+		delivery.LastError = err.Error()
+		retryIn = d.backoff(delivery.Attempts)
+	}
+
+	// результат сохраняем и при остановке сервиса, иначе попытка повторится после lease
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := d.repo.UpdateDelivery(saveCtx, delivery, retryIn); err != nil {
+		log.Printf("Ошибка сохранения результата вебхука %d: %v", delivery.ID, err)
+	}
+}
+
+// отправляет подписанное уведомление; успехом считается любой ответ 2xx
+func (d *Dispatcher) send(ctx context.Context, delivery database.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	return resp.StatusCode, fmt.Errorf("получатель ответил %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+}
+
+// задержка перед следующей попыткой: RetryBase * 2^(attempts-1), не больше RetryMax
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.RetryBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.opts.RetryMax {
+			return d.opts.RetryMax
+		}
+	}
+	return delay
+}
@@ -0,0 +1,22 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"order-service/internal/database"
+)
+
+// интерфейс управления подписками и доставкой уведомлений
+type Manager interface {
+	// ставит уведомления о заказе в очередь подходящим подпискам в транзакции сохранения заказа
+	EnqueueOrder(ctx context.Context, tx *sql.Tx, order database.Order) error
+	// будит отправку после коммита заказа
+	OrderIngested(order database.Order)
+	CreateSubscription(ctx context.Context, sub database.WebhookSubscription) (database.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]database.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]database.WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryID int64) error
+	// отправляет доставки из очереди до отмены контекста
+	Run(ctx context.Context)
+}
@@ -0,0 +1,282 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"order-service/internal/database"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// репозиторий вебхуков в памяти
+type repoMock struct {
+	mutex      sync.Mutex
+	subs       []database.WebhookSubscription
+	deliveries []database.WebhookDelivery
+	retryIn    []time.Duration
+}
+
+func (m *repoMock) CreateSubscription(ctx context.Context, sub database.WebhookSubscription) (database.WebhookSubscription, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	sub.ID = int64(len(m.subs) + 1)
+	m.subs = append(m.subs, sub)
+	return sub, nil
+}
+
+func (m *repoMock) ListSubscriptions(ctx context.Context) ([]database.WebhookSubscription, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]database.WebhookSubscription(nil), m.subs...), nil
+}
+
+func (m *repoMock) DeleteSubscription(ctx context.Context, id int64) error {
+	return database.ErrWebhookNotFound
+}
+
+func (m *repoMock) EnqueueOrderDeliveries(ctx context.Context, tx *sql.Tx, order database.Order, event string, payload []byte) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	queued := 0
+	for _, sub := range m.subs {
+		if sub.Active && (sub.DeliveryService == "" || sub.DeliveryService == order.DeliveryService) &&
+			(sub.Entry == "" || sub.Entry == order.Entry) {
+			m.deliveries = append(m.deliveries, database.WebhookDelivery{
+				ID:             int64(len(m.deliveries) + 1),
+				SubscriptionID: sub.ID,
+				OrderUID:       order.OrderUID,
+				Event:          event,
+				Payload:        payload,
+				Status:         database.WebhookPending,
+				NextAttemptAt:  time.Now(),
+			})
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+func (m *repoMock) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]database.WebhookDelivery, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var claimed []database.WebhookDelivery
+	for i := range m.deliveries {
+		delivery := &m.deliveries[i]
+		sub := m.subs[delivery.SubscriptionID-1]
+		if delivery.Status != database.WebhookPending || delivery.NextAttemptAt.After(time.Now()) || !sub.Active || len(claimed) == limit {
+			continue
+		}
+		delivery.NextAttemptAt = time.Now().Add(lease)
+		claimedDelivery := *delivery
+		claimedDelivery.URL = sub.URL
+		claimedDelivery.Secret = sub.Secret
+		claimed = append(claimed, claimedDelivery)
+	}
+	return claimed, nil
+}
+
+func (m *repoMock) UpdateDelivery(ctx context.Context, delivery database.WebhookDelivery, retryIn time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delivery.NextAttemptAt = time.Now().Add(retryIn)
+	m.deliveries[delivery.ID-1] = delivery
+	m.retryIn = append(m.retryIn, retryIn)
+	return nil
+}
+
+func (m *repoMock) ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]database.WebhookDelivery, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]database.WebhookDelivery(nil), m.deliveries...), nil
+}
+
+func (m *repoMock) RetryDelivery(ctx context.Context, id int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if id < 1 || int(id) > len(m.deliveries) {
+		return database.ErrWebhookNotFound
+	}
+	m.deliveries[id-1].Status = database.WebhookPending
+	m.deliveries[id-1].Attempts = 0
+	m.deliveries[id-1].NextAttemptAt = time.Now()
+	return nil
+}
+
+// переносит все отложенные попытки на текущий момент
+func (m *repoMock) makeDue() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i := range m.deliveries {
+		m.deliveries[i].NextAttemptAt = time.Now()
+	}
+}
+
+func (m *repoMock) delivery(id int64) database.WebhookDelivery {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.deliveries[id-1]
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	// получатель проверяет подпись и отвечает 500 на первый запрос
+	var mutex sync.Mutex
+	var requests int
+	var received payload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if !Verify("secret", timestamp, body, r.Header.Get(HeaderSignature)) {
+			t.Errorf("Неверная подпись уведомления")
+		}
+		if r.Header.Get(HeaderEvent) != EventOrderCreated || r.Header.Get(HeaderDelivery) != "1" {
+			t.Errorf("Неверные заголовки уведомления: %v", r.Header)
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests++
+		if requests == 1 {
+			http.Error(w, "temporary failure", http.StatusInternalServerError)
+			return
+		}
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := &repoMock{}
+	dispatcher := NewDispatcher(repo, Options{MaxAttempts: 3, RetryBase: time.Minute, RetryMax: time.Hour}).(*Dispatcher)
+	ctx := context.Background()
+
+	if _, err := dispatcher.CreateSubscription(ctx, database.WebhookSubscription{URL: receiver.URL, Secret: "secret", DeliveryService: "meest"}); err != nil {
+		t.Fatalf("Ошибка создания подписки: %v", err)
+	}
+	if _, err := dispatcher.CreateSubscription(ctx, database.WebhookSubscription{URL: receiver.URL, Secret: "secret", DeliveryService: "cdek"}); err != nil {
+		t.Fatalf("Ошибка создания подписки: %v", err)
+	}
+
+	// заказ подходит только под первую подписку
+	if err := dispatcher.EnqueueOrder(ctx, nil, database.Order{OrderUID: "order-1", DeliveryService: "meest"}); err != nil {
+		t.Fatalf("Ошибка постановки уведомлений: %v", err)
+	}
+	if len(repo.deliveries) != 1 {
+		t.Fatalf("Ожидалась 1 доставка, получено %d", len(repo.deliveries))
+	}
+
+	// первая попытка неудачна и откладывается на RetryBase
+	if sent := dispatcher.dispatchDue(ctx); sent != 1 {
+		t.Fatalf("Ожидалась 1 отправка, получено %d", sent)
+	}
+	delivery := repo.delivery(1)
+	if delivery.Status != database.WebhookPending || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusInternalServerError {
+		t.Errorf("Неверное состояние после ошибки: %+v", delivery)
+	}
+	if repo.retryIn[0] != time.Minute {
+		t.Errorf("Ожидалась задержка %v, получено %v", time.Minute, repo.retryIn[0])
+	}
+
+	// до наступления времени повтора доставка не отправляется
+	if sent := dispatcher.dispatchDue(ctx); sent != 0 {
+		t.Errorf("Доставка отправлена раньше времени повтора")
+	}
+
+	repo.makeDue()
+	dispatcher.dispatchDue(ctx)
+	delivery = repo.delivery(1)
+	if delivery.Status != database.WebhookDelivered || delivery.Attempts != 2 || delivery.LastError != "" {
+		t.Errorf("Неверное состояние после доставки: %+v", delivery)
+	}
+	if received.Event != EventOrderCreated || received.Order.OrderUID != "order-1" {
+		t.Errorf("Неверное тело уведомления: %+v", received)
+	}
+
+	// повторная доставка по запросу администратора
+	if err := dispatcher.Redeliver(ctx, 1); err != nil {
+		t.Fatalf("Ошибка повторной доставки: %v", err)
+	}
+	dispatcher.dispatchDue(ctx)
+	if requests != 3 {
+		t.Errorf("Ожидалось 3 запроса к получателю, получено %d", requests)
+	}
+	if err := dispatcher.Redeliver(ctx, 42); !errors.Is(err, database.ErrWebhookNotFound) {
+		t.Errorf("Ожидалась ErrWebhookNotFound, получено %v", err)
+	}
+}
+
+func TestWebhookFailsAfterMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	repo := &repoMock{}
+	dispatcher := NewDispatcher(repo, Options{MaxAttempts: 3, RetryBase: time.Second, RetryMax: 3 * time.Second}).(*Dispatcher)
+	ctx := context.Background()
+
+	if _, err := dispatcher.CreateSubscription(ctx, database.WebhookSubscription{URL: receiver.URL}); err != nil {
+		t.Fatalf("Ошибка создания подписки: %v", err)
+	}
+	if repo.subs[0].Secret == "" {
+		t.Error("Секрет подписки не сгенерирован")
+	}
+
+	dispatcher.EnqueueOrder(ctx, nil, database.Order{OrderUID: "order-1"})
+	for i := 0; i < 3; i++ {
+		repo.makeDue()
+		dispatcher.dispatchDue(ctx)
+	}
+
+	delivery := repo.delivery(1)
+	if delivery.Status != database.WebhookFailed || delivery.Attempts != 3 {
+		t.Errorf("Ожидался статус failed после 3 попыток: %+v", delivery)
+	}
+	// экспоненциальная задержка между попытками, у последней задержки нет
+	expected := []time.Duration{time.Second, 2 * time.Second, 0}
+	for i, retryIn := range repo.retryIn {
+		if retryIn != expected[i] {
+			t.Errorf("Попытка %d: ожидалась задержка %v, получено %v", i+1, expected[i], retryIn)
+		}
+	}
+	if dispatcher.backoff(10) != 3*time.Second {
+		t.Errorf("Задержка не ограничена RetryMax: %v", dispatcher.backoff(10))
+	}
+}
+
+// доставки отключенной подписки не отправляются, новые не ставятся в очередь
+func TestWebhookInactiveSubscription(t *testing.T) {
+	repo := &repoMock{}
+	dispatcher := NewDispatcher(repo, Options{}).(*Dispatcher)
+	ctx := context.Background()
+
+	if _, err := dispatcher.CreateSubscription(ctx, database.WebhookSubscription{URL: "http://127.0.0.1:1/hook"}); err != nil {
+		t.Fatalf("Ошибка создания подписки: %v", err)
+	}
+	dispatcher.EnqueueOrder(ctx, nil, database.Order{OrderUID: "order-1"})
+	repo.subs[0].Active = false
+
+	if sent := dispatcher.dispatchDue(ctx); sent != 0 {
+		t.Errorf("Доставка отключенной подписки отправлена: %d", sent)
+	}
+	dispatcher.EnqueueOrder(ctx, nil, database.Order{OrderUID: "order-2"})
+	if len(repo.deliveries) != 1 {
+		t.Errorf("Ожидалась 1 доставка в очереди, получено %d", len(repo.deliveries))
+	}
+}
+
+func TestWebhookSubscriptionValidation(t *testing.T) {
+	dispatcher := NewDispatcher(&repoMock{}, Options{})
+
+	for _, target := range []string{"", "ftp://example.com", "/relative", "http://"} {
+		_, err := dispatcher.CreateSubscription(context.Background(), database.WebhookSubscription{URL: target})
+		if !errors.Is(err, database.ErrInvalidArgument) {
+			t.Errorf("URL %q: ожидалась ErrInvalidArgument, получено %v", target, err)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+-- Откат уведомлений об изменении заказов
+DROP TRIGGER IF EXISTS items_notify_change ON items;
+DROP TRIGGER IF EXISTS payment_notify_change ON payment;
+DROP TRIGGER IF EXISTS delivery_notify_change ON delivery;
+DROP TRIGGER IF EXISTS orders_notify_change ON orders;
+DROP FUNCTION IF EXISTS notify_order_change();
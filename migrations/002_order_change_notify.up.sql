@@ -0,0 +1,45 @@
+-- Уведомления об изменении заказов для сброса кэша на всех репликах
+
+CREATE OR REPLACE FUNCTION notify_order_change() RETURNS trigger AS $$
+DECLARE
+    operation TEXT := TG_OP;
+BEGIN
+    -- изменение доставки, платежа или товаров для кэша означает изменение заказа
+    IF TG_TABLE_NAME <> 'orders' THEN
+        operation := 'UPDATE';
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM pg_notify('order_changes',
+            json_build_object('op', operation, 'order_uid', OLD.order_uid)::text);
+    END IF;
+
+    IF TG_OP = 'INSERT' OR (TG_OP = 'UPDATE' AND NEW.order_uid <> OLD.order_uid) THEN
+        PERFORM pg_notify('order_changes',
+            json_build_object('op', operation, 'order_uid', NEW.order_uid)::text);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS orders_notify_change ON orders;
+CREATE TRIGGER orders_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON orders
+    FOR EACH ROW EXECUTE FUNCTION notify_order_change();
+
+-- вставка во вложенные таблицы происходит в одной транзакции с заказом и сброса кэша не требует
+DROP TRIGGER IF EXISTS delivery_notify_change ON delivery;
+CREATE TRIGGER delivery_notify_change
+    AFTER UPDATE OR DELETE ON delivery
+    FOR EACH ROW EXECUTE FUNCTION notify_order_change();
+
+DROP TRIGGER IF EXISTS payment_notify_change ON payment;
+CREATE TRIGGER payment_notify_change
+    AFTER UPDATE OR DELETE ON payment
+    FOR EACH ROW EXECUTE FUNCTION notify_order_change();
+
+DROP TRIGGER IF EXISTS items_notify_change ON items;
+CREATE TRIGGER items_notify_change
+    AFTER UPDATE OR DELETE ON items
+    FOR EACH ROW EXECUTE FUNCTION notify_order_change();
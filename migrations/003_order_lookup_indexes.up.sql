@@ -0,0 +1,3 @@
+-- Индексы для поиска заказов по трек-номеру и покупателю
+CREATE INDEX IF NOT EXISTS idx_orders_track_number ON orders(track_number);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id_date_created ON orders(customer_id, date_created DESC);
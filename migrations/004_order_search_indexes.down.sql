@@ -0,0 +1,8 @@
+-- Откат индексов поиска заказов с фильтрами
+DROP INDEX IF EXISTS idx_items_nm_id_order_uid;
+DROP INDEX IF EXISTS idx_items_brand_order_uid;
+DROP INDEX IF EXISTS idx_payment_amount_order_uid;
+DROP INDEX IF EXISTS idx_payment_currency;
+DROP INDEX IF EXISTS idx_payment_provider;
+DROP INDEX IF EXISTS idx_orders_delivery_service_date_created;
+DROP INDEX IF EXISTS idx_orders_date_created_order_uid;
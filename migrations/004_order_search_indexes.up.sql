@@ -0,0 +1,8 @@
+-- Индексы для поиска заказов с фильтрами и постраничным выводом по курсору
+CREATE INDEX IF NOT EXISTS idx_orders_date_created_order_uid ON orders(date_created, order_uid);
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_service_date_created ON orders(delivery_service, date_created);
+CREATE INDEX IF NOT EXISTS idx_payment_provider ON payment(provider);
+CREATE INDEX IF NOT EXISTS idx_payment_currency ON payment(currency);
+CREATE INDEX IF NOT EXISTS idx_payment_amount_order_uid ON payment(amount, order_uid);
+CREATE INDEX IF NOT EXISTS idx_items_brand_order_uid ON items(brand, order_uid);
+CREATE INDEX IF NOT EXISTS idx_items_nm_id_order_uid ON items(nm_id, order_uid);
@@ -0,0 +1,3 @@
+-- Откат полнотекстового поиска по товарам
+DROP INDEX IF EXISTS idx_items_search_vector;
+ALTER TABLE items DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,12 @@
+-- Полнотекстовый поиск заказов по названию и бренду товаров
+
+-- название и бренд индексируются в русской и английской конфигурациях,
+-- совпадение по названию весит больше совпадения по бренду
+ALTER TABLE items ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(brand, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(brand, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_items_search_vector ON items USING GIN (search_vector);
@@ -0,0 +1,3 @@
+-- Откат вебхуков
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
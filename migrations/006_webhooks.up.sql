@@ -0,0 +1,32 @@
+-- Подписки партнеров на уведомления о заказах и журнал доставки
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id               SERIAL PRIMARY KEY,
+    url              TEXT NOT NULL,
+    secret           VARCHAR(255) NOT NULL,
+    -- NULL означает любое значение
+    delivery_service VARCHAR(255),
+    entry            VARCHAR(255),
+    active           BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               BIGSERIAL PRIMARY KEY,
+    subscription_id  INTEGER NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    order_uid        VARCHAR(255) NOT NULL,
+    event            VARCHAR(64) NOT NULL,
+    payload          TEXT NOT NULL,
+    -- pending, delivered, failed
+    status           VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    last_error       TEXT NOT NULL DEFAULT '',
+    next_attempt_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    delivered_at     TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, id DESC);
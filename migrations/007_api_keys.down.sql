@@ -0,0 +1,2 @@
+-- Откат API ключей
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,12 @@
+-- API ключи клиентов; хранится только SHA-256 ключа
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           SERIAL PRIMARY KEY,
+    name         VARCHAR(255) NOT NULL,
+    key_hash     CHAR(64) NOT NULL UNIQUE,
+    -- reader, support, admin
+    role         VARCHAR(16) NOT NULL,
+    active       BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP
+);